@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -31,9 +32,16 @@ func main() {
 			// it's not great to hard-code a specific envvar here but this is
 			// probably the most dangerous one we don't want to blithely print
 			// here. open to suggestions...
-			if !strings.HasPrefix(parts[0], "GITHUB") {
-				env[parts[0]] = parts[1]
+			if strings.HasPrefix(parts[0], "GITHUB") {
+				continue
 			}
+			// proxy URLs can carry a password
+			if strings.HasSuffix(strings.ToUpper(parts[0]), "_PROXY") {
+				if u, err := url.Parse(parts[1]); err == nil {
+					parts[1] = u.Redacted()
+				}
+			}
+			env[parts[0]] = parts[1]
 		} else {
 			log.Fatalf("BUG this shouldn't happen")
 		}
@@ -51,6 +59,8 @@ func main() {
 			"endpoint":     conf.Endpoint,
 			"service_name": conf.Servicename,
 			"insecure":     strconv.FormatBool(conf.Insecure),
+			"compression":  conf.TracesCompression,
+			"proxy":        conf.RedactedProxy(),
 		},
 		"otel": {
 			"trace_id":    sc.TraceID().String(),
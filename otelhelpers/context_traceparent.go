@@ -13,31 +13,34 @@ import (
 
 // ContextWithEnvTraceparent is a helper that looks for the the TRACEPARENT
 // environment variable and if it's set, it grabs the traceparent and
-// adds it to the context it returns. When there is no envvar or it's
-// empty, the original context is returned unmodified.
+// adds it to the context it returns, along with TRACESTATE if that's set
+// too. When there is no envvar or it's empty, the original context is
+// returned unmodified.
 // Depends on global OTel TextMapPropagator.
 func ContextWithEnvTraceparent(ctx context.Context) context.Context {
 	traceparent := os.Getenv("TRACEPARENT")
 	if traceparent != "" {
-		return ContextWithTraceparentString(ctx, traceparent)
+		return ContextWithTraceparentAndTracestateStrings(ctx, traceparent, os.Getenv("TRACESTATE"))
 	}
 	return ctx
 }
 
 // ContextWithLinuxCmdlineTraceparent looks in /proc/cmdline for a traceparent=
 // command line option and returns the context with that value as traceparent
-// if it's there. Does no validation. Returns the original context if there is
-// no cmdline option or if there's an error doing the read.
+// if it's there, and the tracestate= option as tracestate. Does no validation.
+// Returns the original context if there is no cmdline option or if there's an
+// error doing the read.
 // This is Linux-only but should be safe on other operating systems.
 // Depends on global OTel TextMapPropagator.
 func ContextWithCmdlineTraceparent(ctx context.Context) context.Context {
 	tp, err := tpFromCmdline("/proc/cmdline")
-	if err != nil {
+	if err != nil || tp == "" {
 		// what to do with error? is there a way to hit the otel error handler infra?
 		return ctx
 	}
+	ts, _ := valueFromCmdline("/proc/cmdline", "tracestate")
 
-	return ContextWithTraceparentString(ctx, tp)
+	return ContextWithTraceparentAndTracestateStrings(ctx, tp, ts)
 }
 
 // ContextWithCmdlineOrEnvTraceparent checks the environment variable first,
@@ -54,8 +57,21 @@ func ContextWithCmdlineOrEnvTraceparent(ctx context.Context) context.Context {
 // carrier code to get it into a context it returns ready to go.
 // Depends on global OTel TextMapPropagator.
 func ContextWithTraceparentString(ctx context.Context, traceparent string) context.Context {
+	return ContextWithTraceparentAndTracestateStrings(ctx, traceparent, "")
+}
+
+// ContextWithTraceparentAndTracestateStrings takes a W3C traceparent string
+// and tracestate string and returns a context with both in it. Tracestate
+// carries vendor data like the p and r values used for consistent
+// probability sampling, so passing it along keeps sampling decisions
+// consistent across process boundaries. An empty tracestate is ignored.
+// Depends on global OTel TextMapPropagator.
+func ContextWithTraceparentAndTracestateStrings(ctx context.Context, traceparent, tracestate string) context.Context {
 	carrier := SimpleCarrier{}
 	carrier.Set("traceparent", traceparent)
+	if tracestate != "" {
+		carrier.Set("tracestate", tracestate)
+	}
 	prop := otel.GetTextMapPropagator()
 	return prop.Extract(ctx, carrier)
 }
@@ -69,19 +85,36 @@ func TraceparentStringFromContext(ctx context.Context) string {
 	return carrier.Get("traceparent")
 }
 
+// TracestateStringFromContext gets the current trace from the context and
+// returns its W3C tracestate string, which is empty when the trace has no
+// tracestate. Pass it along with the traceparent, e.g. as TRACESTATE next to
+// TRACEPARENT. Depends on global OTel TextMapPropagator.
+func TracestateStringFromContext(ctx context.Context) string {
+	carrier := SimpleCarrier{}
+	prop := otel.GetTextMapPropagator()
+	prop.Inject(ctx, carrier)
+	return carrier.Get("tracestate")
+}
+
 // tpFromCmdline reads a /proc/cmdline style file, parses it, and returns whatever
 // value is present for "traceparent=".
 func tpFromCmdline(file string) (string, error) {
+	return valueFromCmdline(file, "traceparent")
+}
+
+// valueFromCmdline reads a /proc/cmdline style file, parses it, and returns
+// whatever value is present for "key=".
+func valueFromCmdline(file, key string) (string, error) {
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
 		return "", err
 	}
 
-	if bytes.Contains(data, []byte("traceparent=")) {
-		kvpairs := bytes.Split(data, []byte(" "))
+	if bytes.Contains(data, []byte(key+"=")) {
+		kvpairs := bytes.Fields(data)
 		for _, kv := range kvpairs {
 			parts := bytes.SplitN(kv, []byte("="), 2)
-			if string(parts[0]) == "traceparent" {
+			if string(parts[0]) == key && len(parts) == 2 {
 				return string(parts[1]), nil
 			}
 		}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/equinix-labs/otel-init-go/otelinit"
@@ -85,3 +86,53 @@ func TestContextWithTraceparentString(t *testing.T) {
 		t.Errorf("expected %q got %q", testTp, tp)
 	}
 }
+
+func TestContextWithTraceparentAndTracestateStrings(t *testing.T) {
+	testTp := "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01"
+	testTs := "ot=p:2;r:5,vendor=x"
+
+	ctx := ContextWithTraceparentAndTracestateStrings(context.Background(), testTp, testTs)
+	if tp := TraceparentStringFromContext(ctx); tp != testTp {
+		t.Errorf("expected traceparent %q got %q", testTp, tp)
+	}
+	if ts := TracestateStringFromContext(ctx); ts != testTs {
+		t.Errorf("expected tracestate %q got %q", testTs, ts)
+	}
+
+	ctx = ContextWithTraceparentString(context.Background(), testTp)
+	if ts := TracestateStringFromContext(ctx); ts != "" {
+		t.Errorf("expected no tracestate, got %q", ts)
+	}
+}
+
+func TestContextWithEnvTracestate(t *testing.T) {
+	t.Setenv("TRACEPARENT", "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01")
+	t.Setenv("TRACESTATE", "ot=p:2;r:5")
+
+	ctx := ContextWithEnvTraceparent(context.Background())
+	sc := trace.SpanContextFromContext(ctx)
+	if got := sc.TraceState().Get("ot"); got != "p:2;r:5" {
+		t.Errorf("expected ot tracestate %q, got %q", "p:2;r:5", got)
+	}
+}
+
+func TestValueFromCmdline(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cmdline")
+	cmdline := "foo=bar traceparent=00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01 tracestate=ot=p:2;r:5\n"
+	if err := os.WriteFile(file, []byte(cmdline), 0o600); err != nil {
+		t.Fatalf("unable to write cmdline test file: %s", err)
+	}
+
+	got, err := valueFromCmdline(file, "tracestate")
+	if err != nil {
+		t.Errorf("reading cmdline test file failed unexpectedly: %s", err)
+	}
+	if got != "ot=p:2;r:5" {
+		t.Errorf("valueFromCmdline comparison failed, expected %q, got %q", "ot=p:2;r:5", got)
+	}
+
+	got, err = valueFromCmdline(file, "initrd")
+	if err != nil || got != "" {
+		t.Errorf("expected no value for missing key, got %q, %v", got, err)
+	}
+}
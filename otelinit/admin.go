@@ -0,0 +1,79 @@
+package otelinit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// samplingState is the JSON body served and accepted by SamplingHandler.
+type samplingState struct {
+	Ratio *float64 `json:"ratio"`
+}
+
+// SamplingHandler returns an http.Handler for changing the sample ratio of a
+// running service, e.g. to trace more during an incident. GET returns the
+// current ratio as {"ratio": 0.1}. PUT or POST sets it, from a JSON body in
+// the same form or from a ratio form value. It does no authentication, so
+// only serve it on an admin port or behind something that does.
+func SamplingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			ratio, err := requestedRatio(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if _, ok := SampleRatio(); !ok {
+				http.Error(w, "OpenTelemetry is not running", http.StatusServiceUnavailable)
+				return
+			}
+			if err := SetSampleRatio(ratio); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ratio, ok := SampleRatio()
+		if !ok {
+			http.Error(w, "OpenTelemetry is not running", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(samplingState{Ratio: &ratio})
+	})
+}
+
+// requestedRatio reads the new ratio from a JSON body or a form value.
+func requestedRatio(r *http.Request) (float64, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var state samplingState
+		if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1024)).Decode(&state); err != nil {
+			return 0, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		if state.Ratio == nil {
+			return 0, errors.New("JSON body has no ratio")
+		}
+		return *state.Ratio, nil
+	}
+
+	val := r.FormValue("ratio")
+	if val == "" {
+		return 0, errors.New("no ratio given")
+	}
+	ratio, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ratio %q is not a number", val)
+	}
+	return ratio, nil
+}
@@ -0,0 +1,97 @@
+package otelinit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	h := SamplingHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sampling", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while not running, got %d", rec.Code)
+	}
+
+	currentTracing.Store(&liveTracing{sampler: newSwappableSampler(Config{})})
+	defer currentTracing.Store(nil)
+
+	// these run in order, each change builds on the last
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "get",
+			req:      httptest.NewRequest(http.MethodGet, "/sampling", nil),
+			wantCode: http.StatusOK,
+			wantBody: `{"ratio":1}`,
+		},
+		{
+			name:     "put json",
+			req:      jsonRequest(http.MethodPut, `{"ratio":0.25}`),
+			wantCode: http.StatusOK,
+			wantBody: `{"ratio":0.25}`,
+		},
+		{
+			name:     "json with charset",
+			req:      withContentType(jsonRequest(http.MethodPut, `{"ratio":0.75}`), "application/json; charset=utf-8"),
+			wantCode: http.StatusOK,
+			wantBody: `{"ratio":0.75}`,
+		},
+		{
+			name:     "post form",
+			req:      httptest.NewRequest(http.MethodPost, "/sampling?ratio=0.5", nil),
+			wantCode: http.StatusOK,
+			wantBody: `{"ratio":0.5}`,
+		},
+		{
+			name:     "out of range",
+			req:      jsonRequest(http.MethodPut, `{"ratio":2}`),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "no ratio",
+			req:      jsonRequest(http.MethodPut, `{}`),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "delete",
+			req:      httptest.NewRequest(http.MethodDelete, "/sampling", nil),
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tc.req)
+			if rec.Code != tc.wantCode {
+				t.Errorf("expected status %d, got %d: %s", tc.wantCode, rec.Code, rec.Body)
+			}
+			if tc.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tc.wantBody {
+				t.Errorf("expected body %s, got %s", tc.wantBody, rec.Body)
+			}
+		})
+	}
+
+	if ratio, _ := SampleRatio(); ratio != 0.5 {
+		t.Errorf("expected the failed requests to leave the ratio at 0.5, got %v", ratio)
+	}
+}
+
+func jsonRequest(method, body string) *http.Request {
+	req := httptest.NewRequest(method, "/sampling", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func withContentType(req *http.Request, contentType string) *http.Request {
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
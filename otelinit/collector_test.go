@@ -0,0 +1,68 @@
+package otelinit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeCollector is an in-process OTLP/gRPC trace receiver that remembers
+// what it was sent, so tests can check what made it over the wire.
+type fakeCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu       sync.Mutex
+	names    []string
+	metadata []metadata.MD
+	addr     string
+}
+
+// startFakeCollector listens on network/address and serves OTLP traces
+// until the test is over.
+func startFakeCollector(t *testing.T, network, address string, opts ...grpc.ServerOption) *fakeCollector {
+	t.Helper()
+
+	lis, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("fake collector could not listen on %s %s: %s", network, address, err)
+	}
+
+	fc := &fakeCollector{addr: lis.Addr().String()}
+	srv := grpc.NewServer(opts...)
+	coltracepb.RegisterTraceServiceServer(srv, fc)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return fc
+}
+
+// Export implements the OTLP trace service.
+func (fc *fakeCollector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	fc.metadata = append(fc.metadata, md)
+
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				fc.names = append(fc.names, span.GetName())
+			}
+		}
+	}
+
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// spanNames returns the names of all spans received so far.
+func (fc *fakeCollector) spanNames() []string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return append([]string{}, fc.names...)
+}
@@ -1,9 +1,13 @@
 package otelinit
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds the typed values of configuration read from the environment.
@@ -13,11 +17,91 @@ type Config struct {
 	Servicename string `json:"service_name"`
 	Endpoint    string `json:"endpoint"`
 	Insecure    bool   `json:"insecure"`
+	// Endpoints is the list of collectors to fail over between, in order
+	// of preference. Endpoint is always the first of them.
+	Endpoints []string `json:"endpoints"`
+	// DNSRefresh is how often a srv+dns:// Endpoint is looked up again
+	// while exporting. See ResolvedEndpoints for what it resolved to.
+	DNSRefresh time.Duration `json:"dns_refresh"`
+	// TraceRouting is "traceid" to send all the spans of a trace to the
+	// same collector, picked by consistent hashing from Endpoints or from
+	// the collectors found at TraceRoutingDNS, which is either a host:port
+	// or an SRV name and is looked up every TraceRoutingRefresh.
+	TraceRouting        string        `json:"trace_routing"`
+	TraceRoutingDNS     string        `json:"trace_routing_dns"`
+	TraceRoutingRefresh time.Duration `json:"trace_routing_refresh"`
+
+	// TLS files, as in the OpenTelemetry spec. They are reloaded when they
+	// change on disk.
+	CertificateFile       string `json:"certificate_file"`
+	ClientCertificateFile string `json:"client_certificate_file"`
+	ClientKeyFile         string `json:"client_key_file"`
+	// Proxy is the HTTP CONNECT proxy used to reach Endpoint, if any. It
+	// may contain a password, so it is left out of JSON; see RedactedProxy.
+	Proxy string `json:"-"`
+	// AllowInsecureRemote permits plaintext export to endpoints that are
+	// not on this machine. Without it, that configuration is refused.
+	AllowInsecureRemote bool `json:"allow_insecure_remote"`
+
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+
+	// Timeout is the per-export deadline. Zero means the exporter's default.
+	Timeout time.Duration `json:"timeout"`
+	// RetryDisabled turns off the exporter's retries on failed exports.
+	RetryDisabled bool `json:"retry_disabled"`
+	// Retry intervals. Zero means the exporter's default for that value.
+	RetryInitialInterval time.Duration `json:"retry_initial_interval"`
+	RetryMaxInterval     time.Duration `json:"retry_max_interval"`
+	RetryMaxElapsedTime  time.Duration `json:"retry_max_elapsed_time"`
+
+	// Compression is "gzip", "none", or empty for the exporter's default.
+	// TracesCompression is what the trace exporter uses: the traces-specific
+	// setting when there is one, otherwise Compression.
+	Compression       string `json:"compression"`
+	TracesCompression string `json:"traces_compression"`
+
+	// HeadersFile and BearerTokenFile are paths to files holding OTLP
+	// headers and a bearer token. They are re-read periodically.
+	HeadersFile     string `json:"headers_file"`
+	BearerTokenFile string `json:"bearer_token_file"`
+
+	// gRPC connection tuning. Zero values leave gRPC's defaults alone.
+	// KeepaliveTime enables keepalive pings. LoadBalancing is "pick_first"
+	// or "round_robin"; round_robin spreads exports over every address the
+	// endpoint's name resolves to, which keeps long-lived processes from
+	// getting stuck on one collector behind an L4 load balancer.
+	KeepaliveTime                time.Duration `json:"keepalive_time"`
+	KeepaliveTimeout             time.Duration `json:"keepalive_timeout"`
+	KeepalivePermitWithoutStream bool          `json:"keepalive_permit_without_stream"`
+	MaxSendMsgSize               int           `json:"max_send_msg_size"`
+	LoadBalancing                string        `json:"load_balancing"`
+
+	// Sampler and SamplerArg are OTEL_TRACES_SAMPLER and its argument.
+	// Empty means parentbased_always_on. SamplerRulesFile is a JSON or
+	// YAML file of rules that are checked before Sampler.
+	Sampler          string `json:"sampler"`
+	SamplerArg       string `json:"sampler_arg"`
+	SamplerRulesFile string `json:"sampler_rules_file"`
+
+	// TracesExporters lists the trace exporters from OTEL_TRACES_EXPORTER:
+	// otlp, console, file, or none. Empty means otlp. TracesFile is where
+	// the file exporter writes.
+	TracesExporters []string `json:"traces_exporters"`
+	TracesFile      string   `json:"traces_file"`
+
+	// OAuth2 client-credentials settings. Export is authenticated with
+	// OAuth2 when OAuth2TokenURL is set.
+	OAuth2TokenURL         string   `json:"oauth2_token_url"`
+	OAuth2ClientID         string   `json:"oauth2_client_id"`
+	OAuth2ClientSecretFile string   `json:"oauth2_client_secret_file"`
+	OAuth2Scopes           []string `json:"oauth2_scopes"`
 }
 
 // newConfig reads all of the documented environment variables and returns a
-// config struct.
-func newConfig(serviceName string) Config {
+// config struct. Bad values are mostly logged and ignored, but an invalid
+// endpoint is returned as an error along with a config with no endpoint, so
+// that otelinit stays inert rather than failing later on.
+func newConfig(serviceName string) (Config, error) {
 	// Use stdlib to parse. If it's an invalid value and doesn't parse, log it
 	// and keep going. It should already be false on error but we force it to
 	// be extra clear that it's failing closed.
@@ -28,15 +112,362 @@ func newConfig(serviceName string) Config {
 		insecure, err = strconv.ParseBool(isEnv)
 		if err != nil {
 			insecure = false
-			log.Println("Invalid boolean value in OTEL_EXPORTER_OTLP_INSECURE. Try true or false.")
+			logger().Warn("invalid boolean value, try true or false",
+				"env", "OTEL_EXPORTER_OTLP_INSECURE", "value", isEnv)
 		}
 	} else {
 		insecure = false
 	}
 
+	var endpoint, scheme string
+	var endpoints []string
+	var endpointErr error
+	if list := envList("OTEL_INIT_ENDPOINTS"); len(list) > 0 {
+		// the failover list wins over the single endpoint, and the first
+		// entry decides the scheme for all of them
+		for i, raw := range list {
+			ep, s, err := parseEndpoint(raw)
+			if err != nil {
+				endpoints = nil
+				endpointErr = fmt.Errorf("invalid endpoint %q in OTEL_INIT_ENDPOINTS: %w", raw, err)
+				break
+			}
+			if i == 0 {
+				endpoint, scheme = ep, s
+			}
+			endpoints = append(endpoints, ep)
+		}
+		if endpointErr != nil {
+			endpoint = ""
+		}
+	} else if epEnv := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); epEnv != "" {
+		endpoint, scheme, endpointErr = parseEndpoint(epEnv)
+		if endpointErr != nil {
+			endpoint = ""
+			endpointErr = fmt.Errorf("invalid OTEL_EXPORTER_OTLP_ENDPOINT %q: %w", epEnv, endpointErr)
+		}
+	}
+
+	traceRouting := envTraceRouting("OTEL_INIT_TRACE_ROUTING")
+	var routingDNS string
+	if dnsEnv := os.Getenv("OTEL_INIT_TRACE_ROUTING_DNS"); dnsEnv != "" && endpointErr == nil {
+		switch {
+		case traceRouting != "traceid":
+			logger().Warn("collector lookup only applies to trace ID routing, set OTEL_INIT_TRACE_ROUTING=traceid",
+				"env", "OTEL_INIT_TRACE_ROUTING_DNS")
+		case isSRVName(dnsEnv):
+			routingDNS = dnsEnv
+		default:
+			var s string
+			var err error
+			routingDNS, s, err = parseEndpoint(dnsEnv)
+			if err == nil && s == "unix" {
+				err = errors.New("unix sockets can't be looked up in DNS")
+			}
+			if err != nil {
+				routingDNS, endpoint, endpoints = "", "", nil
+				endpointErr = fmt.Errorf("invalid OTEL_INIT_TRACE_ROUTING_DNS %q: %w", dnsEnv, err)
+			}
+		}
+	}
+
+	// As in the spec, an http:// endpoint means plaintext unless
+	// OTEL_EXPORTER_OTLP_INSECURE says otherwise. Unix sockets are local and
+	// trusted, so they default to plaintext too.
+	if isEnv == "" && (scheme == "http" || scheme == "unix") {
+		insecure = true
+	}
+
+	// Spans can carry tokens and other secrets in their attributes, so only
+	// send them in the clear across the network when explicitly asked to.
+	allowInsecureRemote := envBool("OTEL_INIT_ALLOW_INSECURE_REMOTE", false)
+	if insecure {
+		if err := checkPlaintextEndpoints(endpoint, endpoints, routingDNS, allowInsecureRemote); err != nil {
+			endpointErr = err
+			endpoint, endpoints, routingDNS = "", nil, ""
+		}
+	}
+
+	if traceRouting == "traceid" && routingDNS == "" && endpoint != "" && len(endpoints) < 2 {
+		logger().Warn("trace ID routing has only one collector to route to, set OTEL_INIT_ENDPOINTS or OTEL_INIT_TRACE_ROUTING_DNS",
+			"env", "OTEL_INIT_TRACE_ROUTING")
+	}
+
+	// both would send an authorization header, and the collector would only
+	// look at one of them
+	bearerTokenFile := os.Getenv("OTEL_INIT_BEARER_TOKEN_FILE")
+	if bearerTokenFile != "" && os.Getenv("OTEL_INIT_OAUTH2_TOKEN_URL") != "" {
+		logger().Warn("a bearer token and OAuth2 can't both be used, ignoring the bearer token",
+			"env", "OTEL_INIT_BEARER_TOKEN_FILE")
+		bearerTokenFile = ""
+	}
+
+	compression := envCompression("OTEL_EXPORTER_OTLP_COMPRESSION")
+	tracesCompression := envCompression("OTEL_EXPORTER_OTLP_TRACES_COMPRESSION")
+	if tracesCompression == "" {
+		tracesCompression = compression
+	}
+
 	return Config{
 		Servicename: serviceName,
-		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		Endpoint:    endpoint,
+		Endpoints:   endpoints,
 		Insecure:    insecure,
+		// zero means use defaultDNSRefresh, see dns.go
+		DNSRefresh:          envDuration("OTEL_INIT_DNS_REFRESH"),
+		TraceRouting:        traceRouting,
+		TraceRoutingDNS:     routingDNS,
+		TraceRoutingRefresh: envDuration("OTEL_INIT_TRACE_ROUTING_REFRESH"),
+		// zero means use defaultShutdownTimeout, see shutdown.go
+		ShutdownTimeout:              envDuration("OTEL_INIT_SHUTDOWN_TIMEOUT"),
+		Timeout:                      envMilliseconds("OTEL_EXPORTER_OTLP_TIMEOUT"),
+		RetryDisabled:                !envBool("OTEL_INIT_RETRY_ENABLED", true),
+		RetryInitialInterval:         envDuration("OTEL_INIT_RETRY_INITIAL_INTERVAL"),
+		RetryMaxInterval:             envDuration("OTEL_INIT_RETRY_MAX_INTERVAL"),
+		RetryMaxElapsedTime:          envDuration("OTEL_INIT_RETRY_MAX_ELAPSED_TIME"),
+		Compression:                  compression,
+		TracesCompression:            tracesCompression,
+		AllowInsecureRemote:          allowInsecureRemote,
+		HeadersFile:                  os.Getenv("OTEL_INIT_HEADERS_FILE"),
+		BearerTokenFile:              bearerTokenFile,
+		OAuth2TokenURL:               os.Getenv("OTEL_INIT_OAUTH2_TOKEN_URL"),
+		OAuth2ClientID:               os.Getenv("OTEL_INIT_OAUTH2_CLIENT_ID"),
+		OAuth2ClientSecretFile:       os.Getenv("OTEL_INIT_OAUTH2_CLIENT_SECRET_FILE"),
+		OAuth2Scopes:                 envList("OTEL_INIT_OAUTH2_SCOPES"),
+		CertificateFile:              os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE"),
+		ClientCertificateFile:        os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"),
+		ClientKeyFile:                os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_KEY"),
+		Proxy:                        proxyForEndpoint(endpoint),
+		KeepaliveTime:                envDuration("OTEL_INIT_GRPC_KEEPALIVE_TIME"),
+		KeepaliveTimeout:             envDuration("OTEL_INIT_GRPC_KEEPALIVE_TIMEOUT"),
+		KeepalivePermitWithoutStream: envBool("OTEL_INIT_GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", false),
+		MaxSendMsgSize:               envBytes("OTEL_INIT_GRPC_MAX_SEND_MSG_SIZE"),
+		LoadBalancing:                envLoadBalancing("OTEL_INIT_GRPC_LOAD_BALANCING"),
+		TracesExporters:              envTracesExporters("OTEL_TRACES_EXPORTER"),
+		TracesFile:                   os.Getenv("OTEL_INIT_TRACES_FILE"),
+		Sampler:                      envSampler("OTEL_TRACES_SAMPLER"),
+		SamplerArg:                   os.Getenv("OTEL_TRACES_SAMPLER_ARG"),
+		SamplerRulesFile:             os.Getenv("OTEL_INIT_SAMPLER_RULES_FILE"),
+	}, endpointErr
+}
+
+// checkPlaintextEndpoints refuses plaintext export to any of the endpoints
+// that isn't on this machine, unless allowed, in which case it only warns.
+func checkPlaintextEndpoints(endpoint string, endpoints []string, routingDNS string, allow bool) error {
+	checked := endpoints
+	if checked == nil && endpoint != "" {
+		checked = []string{endpoint}
+	}
+	if routingDNS != "" {
+		checked = append(checked[:len(checked):len(checked)], routingDNS)
+	}
+
+	for _, ep := range checked {
+		if isLocalEndpoint(ep) {
+			continue
+		}
+		if !allow {
+			return fmt.Errorf("refusing to send plaintext telemetry to remote endpoint %q, "+
+				"use TLS or set OTEL_INIT_ALLOW_INSECURE_REMOTE=true", ep)
+		}
+		logger().Warn("sending telemetry in plaintext to a remote endpoint, spans may expose sensitive data",
+			"endpoint", ep, "env", "OTEL_INIT_ALLOW_INSECURE_REMOTE")
+	}
+	return nil
+}
+
+// checkEndpoints validates and normalizes the endpoints of a config built
+// in code, the way newConfig does for the environment. An http:// or unix://
+// endpoint turns Insecure on, since there is no way to tell an unset
+// Insecure from an explicit false. Proxy is taken from the environment when
+// the caller left it empty, and plaintext to remote endpoints is refused
+// without AllowInsecureRemote.
+func (c Config) checkEndpoints() (Config, error) {
+	var scheme string
+	if c.Endpoint != "" {
+		ep, s, err := parseEndpoint(c.Endpoint)
+		if err != nil {
+			return c, fmt.Errorf("invalid endpoint %q: %w", c.Endpoint, err)
+		}
+		c.Endpoint, scheme = ep, s
+	}
+
+	c.Endpoints = slices.Clone(c.Endpoints)
+	for i, raw := range c.Endpoints {
+		ep, s, err := parseEndpoint(raw)
+		if err != nil {
+			return c, fmt.Errorf("invalid endpoint %q in Endpoints: %w", raw, err)
+		}
+		if i == 0 && c.Endpoint == "" {
+			scheme = s
+		}
+		c.Endpoints[i] = ep
+	}
+
+	if scheme == "http" || scheme == "unix" {
+		c.Insecure = true
+	}
+	if c.Proxy == "" {
+		c.Proxy = proxyForEndpoint(c.Endpoint)
+	}
+
+	if c.TraceRoutingDNS != "" && !isSRVName(c.TraceRoutingDNS) {
+		ep, s, err := parseEndpoint(c.TraceRoutingDNS)
+		if err == nil && s == "unix" {
+			err = errors.New("unix sockets can't be looked up in DNS")
+		}
+		if err != nil {
+			return c, fmt.Errorf("invalid TraceRoutingDNS %q: %w", c.TraceRoutingDNS, err)
+		}
+		c.TraceRoutingDNS = ep
+	}
+
+	if c.Insecure {
+		if err := checkPlaintextEndpoints(c.Endpoint, c.Endpoints, c.TraceRoutingDNS, c.AllowInsecureRemote); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// envBool parses a boolean from the named envvar. When it's unset or doesn't
+// parse, def is returned and the bad value is logged.
+func envBool(name string, def bool) bool {
+	val := os.Getenv(name)
+	if val == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		logger().Warn("invalid boolean value, try true or false", "env", name, "value", val)
+		return def
+	}
+	return b
+}
+
+// envList splits a comma-separated envvar into its trimmed, non-empty parts.
+// Returns nil when the envvar is unset or empty.
+func envList(name string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// envBytes parses a positive number of bytes from the named envvar. Unset
+// and invalid values come back as zero, and the bad ones are logged.
+func envBytes(name string) int {
+	val := os.Getenv(name)
+	if val == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		logger().Warn("invalid size, try a number of bytes like 16777216", "env", name, "value", val)
+		return 0
+	}
+	return n
+}
+
+// envLoadBalancing reads a gRPC load balancing policy name from the named
+// envvar. Only the built-in pick_first and round_robin are supported.
+func envLoadBalancing(name string) string {
+	val := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	switch val {
+	case "", "pick_first", "round_robin":
+		return val
+	default:
+		logger().Warn("invalid load balancing policy, try round_robin or pick_first", "env", name, "value", val)
+		return ""
+	}
+}
+
+// envCompression reads an OTLP compression setting from the named envvar.
+// The spec allows "gzip" and "none". Anything else is logged and treated as
+// unset.
+func envCompression(name string) string {
+	val := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	switch val {
+	case "", "gzip", "none":
+		return val
+	default:
+		logger().Warn("invalid compression, try gzip or none", "env", name, "value", val)
+		return ""
+	}
+}
+
+// envDuration parses a Go duration like "10s" from the named envvar. Unset,
+// invalid, and negative values all come back as zero, and the bad ones are
+// logged.
+func envDuration(name string) time.Duration {
+	val := os.Getenv(name)
+	if val == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		logger().Warn("invalid duration, try something like 10s", "env", name, "value", val)
+		return 0
+	}
+	return d
+}
+
+// envMilliseconds parses an integer number of milliseconds from the named
+// envvar, the format the OpenTelemetry spec uses for its timeouts. Unset,
+// invalid, and negative values all come back as zero, and the bad ones are
+// logged.
+func envMilliseconds(name string) time.Duration {
+	val := os.Getenv(name)
+	if val == "" {
+		return 0
+	}
+
+	ms, err := strconv.Atoi(val)
+	if err != nil || ms < 0 {
+		logger().Warn("invalid timeout, try a number of milliseconds like 10000", "env", name, "value", val)
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// envTracesExporters parses a comma-separated list of trace exporter names.
+// Unknown names are logged and dropped, and so are duplicates. none only
+// counts when it's the only name given.
+func envTracesExporters(name string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, val := range envList(name) {
+		val = strings.ToLower(val)
+		switch {
+		case seen[val]:
+			continue
+		case val == "otlp", val == "console", val == "file":
+			out = append(out, val)
+		case val == "none" && len(envList(name)) == 1:
+			return []string{"none"}
+		default:
+			logger().Warn("invalid trace exporter, try otlp, console, file, or none", "env", name, "value", val)
+		}
+		seen[val] = true
+	}
+	return out
+}
+
+// envTraceRouting parses the trace routing mode. Only traceid changes
+// anything, failover is what multiple endpoints do by default.
+func envTraceRouting(name string) string {
+	val := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	switch val {
+	case "", "failover", "traceid":
+		return val
+	default:
+		logger().Warn("invalid trace routing, try traceid or failover", "env", name, "value", val)
+		return ""
 	}
 }
@@ -3,6 +3,7 @@ package otelinit
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -13,6 +14,7 @@ func TestNewConfig(t *testing.T) {
 	tests := map[string]struct {
 		envIn      map[string]string
 		wantConfig Config
+		wantErr    bool
 	}{
 		"empty env gets empty config": {
 			envIn: map[string]string{},
@@ -58,18 +60,457 @@ func TestNewConfig(t *testing.T) {
 				Endpoint:    "localhost:4317",
 			},
 		},
-		// TODO: maybe should NOT do this, and have newConfig() check
-		// incoming values and ignore obviously bad ones
-		"otlp endpoint allows arbitrary value": {
+		"shutdown timeout parses": {
 			envIn: map[string]string{
-				"OTEL_EXPORTER_OTLP_ENDPOINT": "asdf asdf asdf",
+				"OTEL_INIT_SHUTDOWN_TIMEOUT": "10s",
+			},
+			wantConfig: Config{
+				Servicename:     testServiceName,
+				ShutdownTimeout: 10 * time.Second,
+			},
+		},
+		"invalid shutdown timeout is ignored": {
+			envIn: map[string]string{
+				"OTEL_INIT_SHUTDOWN_TIMEOUT": "a while",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+		},
+		"timeout and retry policy": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_TIMEOUT":       "2500",
+				"OTEL_INIT_RETRY_ENABLED":          "true",
+				"OTEL_INIT_RETRY_INITIAL_INTERVAL": "100ms",
+				"OTEL_INIT_RETRY_MAX_INTERVAL":     "1s",
+				"OTEL_INIT_RETRY_MAX_ELAPSED_TIME": "10m",
+			},
+			wantConfig: Config{
+				Servicename:          testServiceName,
+				Timeout:              2500 * time.Millisecond,
+				RetryInitialInterval: 100 * time.Millisecond,
+				RetryMaxInterval:     time.Second,
+				RetryMaxElapsedTime:  10 * time.Minute,
+			},
+		},
+		"retry can be disabled": {
+			envIn: map[string]string{
+				"OTEL_INIT_RETRY_ENABLED": "false",
+			},
+			wantConfig: Config{
+				Servicename:   testServiceName,
+				RetryDisabled: true,
+			},
+		},
+		"invalid timeout is ignored": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_TIMEOUT": "10s",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+		},
+		"gzip compression applies to traces": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_COMPRESSION": "gzip",
+			},
+			wantConfig: Config{
+				Servicename:       testServiceName,
+				Compression:       "gzip",
+				TracesCompression: "gzip",
+			},
+		},
+		"traces compression overrides general compression": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_COMPRESSION":        "gzip",
+				"OTEL_EXPORTER_OTLP_TRACES_COMPRESSION": "none",
+			},
+			wantConfig: Config{
+				Servicename:       testServiceName,
+				Compression:       "gzip",
+				TracesCompression: "none",
+			},
+		},
+		"invalid compression is ignored": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_COMPRESSION": "zstd",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+		},
+		"unix socket endpoint is insecure by default": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "unix:///run/otel/otlp.sock",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Insecure:    true,
+				Endpoint:    "unix:///run/otel/otlp.sock",
+			},
+		},
+		"unix socket endpoint respects explicit insecure": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "unix:///run/otel/otlp.sock",
+				"OTEL_EXPORTER_OTLP_INSECURE": "false",
 			},
 			wantConfig: Config{
 				Servicename: testServiceName,
 				Insecure:    false,
-				Endpoint:    "asdf asdf asdf",
+				Endpoint:    "unix:///run/otel/otlp.sock",
+			},
+		},
+		"otlp endpoint gets default port": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "collector.example.com",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Endpoint:    "collector.example.com:4317",
+			},
+		},
+		"http endpoint defaults to insecure": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Insecure:    true,
+				Endpoint:    "localhost:4317",
+			},
+		},
+		"https endpoint defaults to secure": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "https://collector.example.com:443/",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Endpoint:    "collector.example.com:443",
+			},
+		},
+		"explicit insecure overrides http scheme": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "http://[::1]:4317",
+				"OTEL_EXPORTER_OTLP_INSECURE": "false",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Endpoint:    "[::1]:4317",
+			},
+		},
+		"ipv6 url without port": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "http://[::1]",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Endpoint:    "[::1]:4317",
+				Insecure:    true,
+			},
+		},
+		"ipv6 literal without port": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "[::1]",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Endpoint:    "[::1]:4317",
+			},
+		},
+		"plaintext to remote endpoint is refused": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "collector.example.com:4317",
+				"OTEL_EXPORTER_OTLP_INSECURE": "true",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Insecure:    true,
+			},
+			wantErr: true,
+		},
+		"http scheme to remote endpoint is refused": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "http://10.1.2.3:4317",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Insecure:    true,
+			},
+			wantErr: true,
+		},
+		"plaintext to remote endpoint with opt-in": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":     "collector.example.com:4317",
+				"OTEL_EXPORTER_OTLP_INSECURE":     "true",
+				"OTEL_INIT_ALLOW_INSECURE_REMOTE": "true",
+			},
+			wantConfig: Config{
+				Servicename:         testServiceName,
+				Insecure:            true,
+				Endpoint:            "collector.example.com:4317",
+				AllowInsecureRemote: true,
+			},
+		},
+		"plaintext to loopback ip is allowed": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "127.0.0.1:4317",
+				"OTEL_EXPORTER_OTLP_INSECURE": "true",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Insecure:    true,
+				Endpoint:    "127.0.0.1:4317",
+			},
+		},
+		"oauth2 client credentials": {
+			envIn: map[string]string{
+				"OTEL_INIT_OAUTH2_TOKEN_URL":          "https://auth.example.com/oauth2/token",
+				"OTEL_INIT_OAUTH2_CLIENT_ID":          "my-service",
+				"OTEL_INIT_OAUTH2_CLIENT_SECRET_FILE": "/etc/otel/client-secret",
+				"OTEL_INIT_OAUTH2_SCOPES":             "traces:write, metrics:write",
+			},
+			wantConfig: Config{
+				Servicename:            testServiceName,
+				OAuth2TokenURL:         "https://auth.example.com/oauth2/token",
+				OAuth2ClientID:         "my-service",
+				OAuth2ClientSecretFile: "/etc/otel/client-secret",
+				OAuth2Scopes:           []string{"traces:write", "metrics:write"},
+			},
+		},
+		"oauth2 wins over a bearer token": {
+			envIn: map[string]string{
+				"OTEL_INIT_BEARER_TOKEN_FILE": "/var/run/secrets/tokens/otel",
+				"OTEL_INIT_OAUTH2_TOKEN_URL":  "https://auth.example.com/oauth2/token",
+			},
+			wantConfig: Config{
+				Servicename:    testServiceName,
+				OAuth2TokenURL: "https://auth.example.com/oauth2/token",
+			},
+		},
+		"tls files": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_CERTIFICATE":        "/etc/otel/ca.crt",
+				"OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE": "/etc/otel/tls.crt",
+				"OTEL_EXPORTER_OTLP_CLIENT_KEY":         "/etc/otel/tls.key",
+			},
+			wantConfig: Config{
+				Servicename:           testServiceName,
+				CertificateFile:       "/etc/otel/ca.crt",
+				ClientCertificateFile: "/etc/otel/tls.crt",
+				ClientKeyFile:         "/etc/otel/tls.key",
+			},
+		},
+		"grpc dial tuning": {
+			envIn: map[string]string{
+				"OTEL_INIT_GRPC_KEEPALIVE_TIME":                  "30s",
+				"OTEL_INIT_GRPC_KEEPALIVE_TIMEOUT":               "5s",
+				"OTEL_INIT_GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM": "true",
+				"OTEL_INIT_GRPC_MAX_SEND_MSG_SIZE":               "16777216",
+				"OTEL_INIT_GRPC_LOAD_BALANCING":                  "round_robin",
+			},
+			wantConfig: Config{
+				Servicename:                  testServiceName,
+				KeepaliveTime:                30 * time.Second,
+				KeepaliveTimeout:             5 * time.Second,
+				KeepalivePermitWithoutStream: true,
+				MaxSendMsgSize:               16777216,
+				LoadBalancing:                "round_robin",
+			},
+		},
+		"invalid grpc tuning is ignored": {
+			envIn: map[string]string{
+				"OTEL_INIT_GRPC_MAX_SEND_MSG_SIZE": "16MB",
+				"OTEL_INIT_GRPC_LOAD_BALANCING":    "least_request",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+		},
+		"failover endpoints": {
+			envIn: map[string]string{
+				"OTEL_INIT_ENDPOINTS":         "https://collector-a.example.com, collector-b.example.com:4318",
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "ignored.example.com",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Endpoint:    "collector-a.example.com:4317",
+				Endpoints:   []string{"collector-a.example.com:4317", "collector-b.example.com:4318"},
+			},
+		},
+		"failover endpoints reject an invalid entry": {
+			envIn: map[string]string{
+				"OTEL_INIT_ENDPOINTS": "collector-a.example.com,asdf asdf",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+			wantErr: true,
+		},
+		"failover endpoints refuse plaintext to any remote entry": {
+			envIn: map[string]string{
+				"OTEL_INIT_ENDPOINTS": "http://localhost:4317,http://collector-b.example.com:4317",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Insecure:    true,
+			},
+			wantErr: true,
+		},
+		"traces exporters": {
+			envIn: map[string]string{
+				"OTEL_TRACES_EXPORTER":  "otlp, Console,file,otlp,zipkin",
+				"OTEL_INIT_TRACES_FILE": "/var/log/traces.jsonl",
+			},
+			wantConfig: Config{
+				Servicename:     testServiceName,
+				TracesExporters: []string{"otlp", "console", "file"},
+				TracesFile:      "/var/log/traces.jsonl",
+			},
+		},
+		"traces exporter none": {
+			envIn: map[string]string{
+				"OTEL_TRACES_EXPORTER": "none",
+			},
+			wantConfig: Config{
+				Servicename:     testServiceName,
+				TracesExporters: []string{"none"},
+			},
+		},
+		"trace routing over a static list": {
+			envIn: map[string]string{
+				"OTEL_INIT_ENDPOINTS":     "collector-a.example.com,collector-b.example.com",
+				"OTEL_INIT_TRACE_ROUTING": "traceid",
+			},
+			wantConfig: Config{
+				Servicename:  testServiceName,
+				Endpoint:     "collector-a.example.com:4317",
+				Endpoints:    []string{"collector-a.example.com:4317", "collector-b.example.com:4317"},
+				TraceRouting: "traceid",
+			},
+		},
+		"trace routing over SRV records": {
+			envIn: map[string]string{
+				"OTEL_INIT_TRACE_ROUTING":         "traceid",
+				"OTEL_INIT_TRACE_ROUTING_DNS":     "_otlp._tcp.collectors.internal",
+				"OTEL_INIT_TRACE_ROUTING_REFRESH": "1m",
+			},
+			wantConfig: Config{
+				Servicename:         testServiceName,
+				TraceRouting:        "traceid",
+				TraceRoutingDNS:     "_otlp._tcp.collectors.internal",
+				TraceRoutingRefresh: time.Minute,
+			},
+		},
+		"trace routing over A records gets the default port": {
+			envIn: map[string]string{
+				"OTEL_INIT_TRACE_ROUTING":     "traceid",
+				"OTEL_INIT_TRACE_ROUTING_DNS": "collectors.internal",
+			},
+			wantConfig: Config{
+				Servicename:     testServiceName,
+				TraceRouting:    "traceid",
+				TraceRoutingDNS: "collectors.internal:4317",
+			},
+		},
+		"trace routing dns needs traceid routing": {
+			envIn: map[string]string{
+				"OTEL_INIT_TRACE_ROUTING":     "sticky",
+				"OTEL_INIT_TRACE_ROUTING_DNS": "collectors.internal",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+		},
+		"trace routing dns refuses plaintext": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_INSECURE": "true",
+				"OTEL_INIT_TRACE_ROUTING":     "traceid",
+				"OTEL_INIT_TRACE_ROUTING_DNS": "collectors.internal",
+			},
+			wantConfig: Config{
+				Servicename:  testServiceName,
+				Insecure:     true,
+				TraceRouting: "traceid",
+			},
+			wantErr: true,
+		},
+		"sampler": {
+			envIn: map[string]string{
+				"OTEL_TRACES_SAMPLER":     "parentbased_traceidratio",
+				"OTEL_TRACES_SAMPLER_ARG": "0.1",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Sampler:     "parentbased_traceidratio",
+				SamplerArg:  "0.1",
+			},
+		},
+		"rate limiting sampler": {
+			envIn: map[string]string{
+				"OTEL_TRACES_SAMPLER":     "parentbased_ratelimiting",
+				"OTEL_TRACES_SAMPLER_ARG": "100",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Sampler:     "parentbased_ratelimiting",
+				SamplerArg:  "100",
+			},
+		},
+		"consistent probability sampler": {
+			envIn: map[string]string{
+				"OTEL_TRACES_SAMPLER":     "parentbased_consistent_probability",
+				"OTEL_TRACES_SAMPLER_ARG": "0.25",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+				Sampler:     "parentbased_consistent_probability",
+				SamplerArg:  "0.25",
 			},
 		},
+		"unknown sampler is ignored": {
+			envIn: map[string]string{
+				"OTEL_TRACES_SAMPLER": "jaeger_remote",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+		},
+		"otlp endpoint rejects arbitrary value": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "asdf asdf asdf",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+			wantErr: true,
+		},
+		"otlp endpoint rejects srv+dns without a name": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "srv+dns://",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+			wantErr: true,
+		},
+		"otlp endpoint rejects bad port": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:otlp",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+			wantErr: true,
+		},
+		"otlp endpoint rejects unknown scheme": {
+			envIn: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "ftp://localhost:4317",
+			},
+			wantConfig: Config{
+				Servicename: testServiceName,
+			},
+			wantErr: true,
+		},
 	}
 
 	for name, tc := range tests {
@@ -84,7 +525,10 @@ func TestNewConfig(t *testing.T) {
 				}
 			}
 			// generate a config
-			c := newConfig(testServiceName)
+			c, err := newConfig(testServiceName)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("unexpected error state, wanted error: %t, got: %v", tc.wantErr, err)
+			}
 			// see if it's any good
 			if diff := cmp.Diff(c, tc.wantConfig); diff != "" {
 				t.Errorf(diff)
@@ -0,0 +1,193 @@
+package otelinit
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The p and r values of consistent probability sampling, as in the
+// OpenTelemetry spec's tracestate handling for probability sampling. r is
+// shared by every span of a trace, p is the sampling probability 2**-p a
+// span was sampled with, and a span is sampled when p <= r.
+const (
+	otTraceStateKey = "ot"
+	maxPValue       = 63 // zero probability
+	maxRValue       = 62
+)
+
+// consistentSampler makes sampling decisions that agree across services
+// with different ratios. Every trace carries a random r value in its
+// tracestate, set by the first service to see it, and each service samples
+// when its own p value is at most r. For power of two ratios, a service with
+// a higher ratio then samples every trace a service with a lower ratio does,
+// so traces aren't left with holes. Other ratios are rounded to one of the
+// two nearest powers of two at random for each span, so two services whose
+// ratios fall between the same powers of two only agree most of the time.
+type consistentSampler struct {
+	ratio float64
+	// ratios that aren't a power of two are made up of a mix of the two
+	// nearest p values, pLow with probability pLowProb and pLow+1 otherwise
+	pLow     int
+	pLowProb float64
+}
+
+func newConsistentSampler(ratio float64) *consistentSampler {
+	cs := &consistentSampler{ratio: ratio}
+	switch {
+	case ratio <= 0:
+		cs.pLow, cs.pLowProb = maxPValue, 1
+	case ratio >= 1:
+		cs.pLow, cs.pLowProb = 0, 1
+	default:
+		cs.pLow = int(math.Floor(-math.Log2(ratio)))
+		if cs.pLow >= maxPValue {
+			cs.pLow, cs.pLowProb = maxPValue, 1
+			break
+		}
+		high, low := math.Exp2(-float64(cs.pLow)), math.Exp2(-float64(cs.pLow+1))
+		cs.pLowProb = (ratio - low) / (high - low)
+	}
+	return cs
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (cs *consistentSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	ts := trace.SpanContextFromContext(p.ParentContext).TraceState()
+	ot := parseOTTraceState(ts.Get(otTraceStateKey))
+
+	// respect the r value the trace already has, that's what makes the
+	// decisions consistent
+	r, ok := ot.r()
+	if !ok {
+		r = randomRValue()
+	}
+
+	pValue := cs.pLow
+	if cs.pLowProb < 1 && rand.Float64() >= cs.pLowProb {
+		pValue++
+	}
+
+	decision := sdktrace.Drop
+	if pValue < maxPValue && pValue <= r {
+		decision = sdktrace.RecordAndSample
+		ot.set("p", strconv.Itoa(pValue))
+	} else {
+		// p only describes sampled spans
+		ot.unset("p")
+	}
+	ot.set("r", strconv.Itoa(r))
+
+	if updated, err := ts.Insert(otTraceStateKey, ot.String()); err == nil {
+		ts = updated
+	}
+
+	return sdktrace.SamplingResult{Decision: decision, Tracestate: ts}
+}
+
+// Description implements sdktrace.Sampler.
+func (cs *consistentSampler) Description() string {
+	return fmt.Sprintf("ConsistentProbabilityBased{%g}", cs.ratio)
+}
+
+// randomRValue returns an r value: the number of leading zeros in 62 random
+// bits, so r is at least n with probability 2**-n.
+func randomRValue() int {
+	return min(bits.LeadingZeros64(rand.Uint64()>>2)-2, maxRValue)
+}
+
+// otTraceState is the value of the ot tracestate entry, semicolon separated
+// key:value pairs. Pairs other than p and r are kept as they are.
+type otTraceState []otPair
+
+type otPair struct {
+	key, value string
+}
+
+func parseOTTraceState(val string) otTraceState {
+	var ot otTraceState
+	for _, field := range strings.Split(val, ";") {
+		k, v, ok := strings.Cut(field, ":")
+		if !ok || k == "" {
+			continue
+		}
+		ot = append(ot, otPair{key: k, value: v})
+	}
+
+	// drop values out of range rather than trust them
+	if p, ok := ot.get("p"); ok {
+		if n, err := strconv.Atoi(p); err != nil || n < 0 || n > maxPValue {
+			ot.unset("p")
+		}
+	}
+	if _, ok := ot.r(); !ok {
+		ot.unset("r")
+		// p means nothing without r
+		ot.unset("p")
+	}
+	return ot
+}
+
+func (ot otTraceState) get(key string) (string, bool) {
+	for _, pair := range ot {
+		if pair.key == key {
+			return pair.value, true
+		}
+	}
+	return "", false
+}
+
+// r returns the r value when there is a valid one.
+func (ot otTraceState) r() (int, bool) {
+	val, ok := ot.get("r")
+	if !ok {
+		return 0, false
+	}
+	r, err := strconv.Atoi(val)
+	if err != nil || r < 0 || r > maxRValue {
+		return 0, false
+	}
+	return r, true
+}
+
+func (ot *otTraceState) set(key, value string) {
+	for i, pair := range *ot {
+		if pair.key == key {
+			(*ot)[i].value = value
+			return
+		}
+	}
+	*ot = append(*ot, otPair{key: key, value: value})
+}
+
+func (ot *otTraceState) unset(key string) {
+	out := (*ot)[:0]
+	for _, pair := range *ot {
+		if pair.key != key {
+			out = append(out, pair)
+		}
+	}
+	*ot = out
+}
+
+// String formats the pairs back into a tracestate value, p and r first.
+func (ot otTraceState) String() string {
+	fields := make([]string, 0, len(ot))
+	for _, key := range []string{"p", "r"} {
+		if val, ok := ot.get(key); ok {
+			fields = append(fields, key+":"+val)
+		}
+	}
+	for _, pair := range ot {
+		if pair.key != "p" && pair.key != "r" {
+			fields = append(fields, pair.key+":"+pair.value)
+		}
+	}
+	return strings.Join(fields, ";")
+}
@@ -0,0 +1,137 @@
+package otelinit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// parentWithTraceState returns a context with a remote parent span that
+// carries the given tracestate.
+func parentWithTraceState(t *testing.T, tracestate string) context.Context {
+	t.Helper()
+
+	ts, err := trace.ParseTraceState(tracestate)
+	if err != nil {
+		t.Fatalf("bad test tracestate %q: %s", tracestate, err)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: ts,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(context.Background(), sc)
+}
+
+func TestConsistentSamplerPValues(t *testing.T) {
+	tests := map[float64]struct {
+		pLow     int
+		pLowProb float64
+	}{
+		1:     {pLow: 0, pLowProb: 1},
+		0.25:  {pLow: 2, pLowProb: 1},
+		0.3:   {pLow: 1, pLowProb: 0.2},
+		0:     {pLow: maxPValue, pLowProb: 1},
+		1e-30: {pLow: maxPValue, pLowProb: 1},
+	}
+
+	for ratio, want := range tests {
+		cs := newConsistentSampler(ratio)
+		if cs.pLow != want.pLow || cs.pLowProb-want.pLowProb > 1e-9 || want.pLowProb-cs.pLowProb > 1e-9 {
+			t.Errorf("ratio %v: expected p %d with probability %v, got %d with %v",
+				ratio, want.pLow, want.pLowProb, cs.pLow, cs.pLowProb)
+		}
+	}
+}
+
+func TestConsistentSamplerRespectsParent(t *testing.T) {
+	cs := newConsistentSampler(0.25) // p is always 2
+
+	tests := map[string]struct {
+		tracestate string
+		want       sdktrace.SamplingDecision
+		wantState  string
+	}{
+		"r high enough": {
+			tracestate: "ot=r:5,vendor=x",
+			want:       sdktrace.RecordAndSample,
+			wantState:  "ot=p:2;r:5,vendor=x",
+		},
+		"r too low": {
+			tracestate: "ot=p:0;r:1",
+			want:       sdktrace.Drop,
+			wantState:  "ot=r:1",
+		},
+		"other ot values are kept": {
+			tracestate: "ot=th:8;r:2",
+			want:       sdktrace.RecordAndSample,
+			wantState:  "ot=p:2;r:2;th:8",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res := cs.ShouldSample(sdktrace.SamplingParameters{ParentContext: parentWithTraceState(t, tc.tracestate)})
+			if res.Decision != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, res.Decision)
+			}
+			if got := res.Tracestate.String(); got != tc.wantState {
+				t.Errorf("expected tracestate %q, got %q", tc.wantState, got)
+			}
+		})
+	}
+}
+
+func TestConsistentSamplerInvalidParent(t *testing.T) {
+	cs := newConsistentSampler(1)
+	res := cs.ShouldSample(sdktrace.SamplingParameters{ParentContext: parentWithTraceState(t, "ot=p:99;r:70")})
+
+	ot := parseOTTraceState(res.Tracestate.Get(otTraceStateKey))
+	r, ok := ot.r()
+	if !ok || r == 70 {
+		t.Errorf("expected a fresh r value in place of the invalid one, got %q", res.Tracestate.String())
+	}
+	if p, _ := ot.get("p"); p != "0" {
+		t.Errorf("expected p:0 for ratio 1, got %q", res.Tracestate.String())
+	}
+}
+
+func TestConsistentSamplerAgreement(t *testing.T) {
+	high, low := newConsistentSampler(0.5), newConsistentSampler(0.125)
+
+	sampled := 0
+	for r := 0; r <= maxRValue; r++ {
+		ctx := parentWithTraceState(t, "ot=r:"+strconv.Itoa(r))
+		lowSampled := low.ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx}).Decision == sdktrace.RecordAndSample
+		highSampled := high.ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx}).Decision == sdktrace.RecordAndSample
+		if lowSampled && !highSampled {
+			t.Errorf("r %d: sampled at 0.125 but not at 0.5", r)
+		}
+		if lowSampled {
+			sampled++
+		}
+	}
+	if sampled == 0 {
+		t.Error("expected the lower ratio to sample some r values")
+	}
+}
+
+func TestConsistentSamplerRatio(t *testing.T) {
+	cs := newConsistentSampler(0.3)
+
+	const n = 20000
+	sampled := 0
+	for i := 0; i < n; i++ {
+		if cs.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background()}).Decision == sdktrace.RecordAndSample {
+			sampled++
+		}
+	}
+	if got := float64(sampled) / n; got < 0.27 || got > 0.33 {
+		t.Errorf("expected roughly 30%% of traces sampled, got %.3f", got)
+	}
+}
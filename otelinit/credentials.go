@@ -0,0 +1,133 @@
+package otelinit
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// credentialsReloadInterval is how often the headers and bearer token files
+// are re-read, so rotated tokens get picked up without a restart.
+const credentialsReloadInterval = time.Minute
+
+// fileCredentials is a gRPC PerRPCCredentials that reads OTLP headers and a
+// bearer token from files instead of the environment, where they would be
+// visible in /proc/*/environ. The files are re-read when they are older than
+// interval, and the last good values are kept if a read fails.
+type fileCredentials struct {
+	headersFile string
+	tokenFile   string
+	interval    time.Duration
+	secure      bool
+
+	mu       sync.Mutex
+	headers  map[string]string
+	token    string
+	loadedAt time.Time
+}
+
+// newFileCredentials returns nil when neither file is configured.
+func (c Config) newFileCredentials() *fileCredentials {
+	if c.HeadersFile == "" && c.BearerTokenFile == "" {
+		return nil
+	}
+
+	fc := &fileCredentials{
+		headersFile: c.HeadersFile,
+		tokenFile:   c.BearerTokenFile,
+		interval:    credentialsReloadInterval,
+		secure:      !c.Insecure,
+	}
+	fc.reload()
+
+	return fc
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (fc *fileCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if time.Since(fc.loadedAt) >= fc.interval {
+		fc.reload()
+	}
+
+	md := make(map[string]string, len(fc.headers)+1)
+	for k, v := range fc.headers {
+		md[k] = v
+	}
+	if fc.token != "" {
+		md["authorization"] = "Bearer " + fc.token
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. Sending
+// credentials in the clear is only possible when plaintext was already
+// allowed for the endpoint.
+func (fc *fileCredentials) RequireTransportSecurity() bool {
+	return fc.secure
+}
+
+// reload reads both files. A file that can't be read is logged and its
+// previous values are kept. The caller must hold mu, or be the constructor.
+func (fc *fileCredentials) reload() {
+	if fc.headersFile != "" {
+		data, err := os.ReadFile(fc.headersFile)
+		if err != nil {
+			logger().Warn("could not read OTLP headers file, keeping previous headers",
+				"env", "OTEL_INIT_HEADERS_FILE", "path", fc.headersFile, "error", err)
+		} else {
+			fc.headers = parseHeaders(string(data))
+		}
+	}
+
+	if fc.tokenFile != "" {
+		data, err := os.ReadFile(fc.tokenFile)
+		if err != nil {
+			logger().Warn("could not read bearer token file, keeping previous token",
+				"env", "OTEL_INIT_BEARER_TOKEN_FILE", "path", fc.tokenFile, "error", err)
+		} else {
+			fc.token = strings.TrimSpace(string(data))
+		}
+	}
+
+	fc.loadedAt = time.Now()
+}
+
+// parseHeaders parses headers in the OTEL_EXPORTER_OTLP_HEADERS format,
+// key1=value1,key2=value2 with URL-encoded values. Newlines work as
+// separators too and lines starting with # are ignored, which is friendlier
+// for files. Keys are lowercased as gRPC metadata requires.
+func parseHeaders(data string) map[string]string {
+	md := map[string]string{}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, kv := range strings.Split(line, ",") {
+			k, v, ok := strings.Cut(kv, "=")
+			k = strings.ToLower(strings.TrimSpace(k))
+			if !ok || k == "" {
+				continue
+			}
+			v = strings.TrimSpace(v)
+			if unescaped, err := url.QueryUnescape(v); err == nil {
+				v = unescaped
+			}
+			md[k] = v
+		}
+	}
+
+	return md
+}
+
+// make sure fileCredentials stays a PerRPCCredentials
+var _ credentials.PerRPCCredentials = (*fileCredentials)(nil)
@@ -0,0 +1,94 @@
+package otelinit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+)
+
+func TestParseHeaders(t *testing.T) {
+	in := "# collector auth\nX-Api-Key=abc123, tenant=my%20team\n\nbroken\n"
+	want := map[string]string{
+		"x-api-key": "abc123",
+		"tenant":    "my team",
+	}
+
+	if diff := cmp.Diff(want, parseHeaders(in)); diff != "" {
+		t.Errorf("parsed headers did not match (-want +got):\n%s", diff)
+	}
+}
+
+func TestFileCredentialsReload(t *testing.T) {
+	dir := t.TempDir()
+	headersFile := filepath.Join(dir, "headers")
+	tokenFile := filepath.Join(dir, "token")
+	writeFile(t, headersFile, "x-api-key=one")
+	writeFile(t, tokenFile, "token-one\n")
+
+	fc := Config{HeadersFile: headersFile, BearerTokenFile: tokenFile}.newFileCredentials()
+	fc.interval = 0 // reload on every call
+
+	md, _ := fc.GetRequestMetadata(context.Background())
+	want := map[string]string{"x-api-key": "one", "authorization": "Bearer token-one"}
+	if diff := cmp.Diff(want, md); diff != "" {
+		t.Errorf("initial metadata did not match (-want +got):\n%s", diff)
+	}
+
+	// rotate the token, it should be picked up
+	writeFile(t, tokenFile, "token-two\n")
+	md, _ = fc.GetRequestMetadata(context.Background())
+	want["authorization"] = "Bearer token-two"
+	if diff := cmp.Diff(want, md); diff != "" {
+		t.Errorf("rotated metadata did not match (-want +got):\n%s", diff)
+	}
+
+	// a missing file keeps the last good values
+	os.Remove(headersFile)
+	md, _ = fc.GetRequestMetadata(context.Background())
+	if diff := cmp.Diff(want, md); diff != "" {
+		t.Errorf("metadata changed after the headers file went away (-want +got):\n%s", diff)
+	}
+}
+
+func TestFileCredentialsExport(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	writeFile(t, tokenFile, "sekrit")
+
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	ctx := context.Background()
+	c := Config{
+		Servicename:     testServiceName,
+		Endpoint:        fc.addr,
+		Insecure:        true,
+		BearerTokenFile: tokenFile,
+	}
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), options{})
+	_, span := otel.Tracer("test").Start(ctx, "with a token")
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if len(fc.metadata) == 0 {
+		t.Fatal("collector received no exports")
+	}
+	if got := fc.metadata[0].Get("authorization"); len(got) != 1 || got[0] != "Bearer sekrit" {
+		t.Errorf("expected bearer token in export metadata, got %q", got)
+	}
+}
+
+// writeFile writes a test fixture file or fails the test.
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("could not write test file %q: %s", path, err)
+	}
+}
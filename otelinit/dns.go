@@ -0,0 +1,281 @@
+package otelinit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	grpcresolver "google.golang.org/grpc/resolver"
+)
+
+// srvScheme is the prefix for endpoints discovered through DNS SRV records,
+// e.g. srv+dns://_otlp._tcp.collector.internal.
+const srvScheme = "srv+dns://"
+
+// defaultDNSRefresh is how often srv+dns endpoints are looked up again when
+// OTEL_INIT_DNS_REFRESH isn't set.
+const defaultDNSRefresh = 30 * time.Second
+
+// minResolveInterval keeps gRPC's requests to resolve again, which come on
+// every connection failure, from turning into a stream of DNS queries.
+const minResolveInterval = 5 * time.Second
+
+// resolved holds the targets each srv+dns endpoint last resolved to, for
+// ResolvedEndpoints.
+var resolved struct {
+	sync.Mutex
+	targets map[string][]string
+}
+
+// ResolvedEndpoints returns the host:port pairs each srv+dns:// endpoint
+// resolved to the last time it was looked up, keyed by endpoint. Lookups
+// happen in the background while exporting, so an endpoint is missing until
+// its first lookup succeeds. Returns nil when there are none.
+func ResolvedEndpoints() map[string][]string {
+	resolved.Lock()
+	defer resolved.Unlock()
+
+	if len(resolved.targets) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(resolved.targets))
+	for ep, targets := range resolved.targets {
+		out[ep] = slices.Clone(targets)
+	}
+	return out
+}
+
+// setResolved records what an SRV name resolved to, or forgets it when
+// addrs is nil.
+func setResolved(name string, addrs []grpcresolver.Address) {
+	resolved.Lock()
+	defer resolved.Unlock()
+
+	if addrs == nil {
+		delete(resolved.targets, srvScheme+name)
+		return
+	}
+	if resolved.targets == nil {
+		resolved.targets = map[string][]string{}
+	}
+	targets := make([]string, len(addrs))
+	for i, addr := range addrs {
+		targets[i] = addr.Addr
+	}
+	resolved.targets[srvScheme+name] = targets
+}
+
+// dnsResolver does all of otelinit's DNS lookups. Tests swap it out to talk
+// to a DNS server of their own.
+var dnsResolver resolver = net.DefaultResolver
+
+// resolver is the part of net.Resolver that collector discovery uses, so
+// tests can stand in for DNS.
+type resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// isSRVName reports whether name is an SRV record name, like
+// _otlp._tcp.collector.internal, rather than a host:port.
+func isSRVName(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// resolveEndpoints looks up the collectors behind a DNS name. SRV names
+// give host:port pairs directly, anything else is a host:port whose A/AAAA
+// records are each paired with the port. The result is sorted so it can be
+// compared between lookups.
+func resolveEndpoints(ctx context.Context, r resolver, name string) ([]string, error) {
+	var out []string
+
+	if isSRVName(name) {
+		addrs, err := lookupSRVAddresses(ctx, r, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			out = append(out, addr.Addr)
+		}
+	} else {
+		host, port, err := net.SplitHostPort(name)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			out = append(out, net.JoinHostPort(addr, port))
+		}
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no collectors found for %q", name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// isSRVEndpoint reports whether the endpoint is discovered through SRV
+// records.
+func (c Config) isSRVEndpoint() bool {
+	return strings.HasPrefix(c.Endpoint, srvScheme)
+}
+
+// lookupSRVAddresses looks up an SRV name and returns the targets as gRPC
+// addresses, best priority first. Targets with the same priority are sorted
+// by name rather than shuffled by weight, so the order only changes when the
+// records do. Each address carries its target's hostname for TLS, since the
+// SRV name is not what collector certificates are issued for.
+func lookupSRVAddresses(ctx context.Context, r resolver, name string) ([]grpcresolver.Address, error) {
+	_, srvs, err := r.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(srvs) == 0 {
+		return nil, fmt.Errorf("no SRV records found for %q", name)
+	}
+
+	sort.SliceStable(srvs, func(i, j int) bool {
+		if srvs[i].Priority != srvs[j].Priority {
+			return srvs[i].Priority < srvs[j].Priority
+		}
+		if srvs[i].Target != srvs[j].Target {
+			return srvs[i].Target < srvs[j].Target
+		}
+		return srvs[i].Port < srvs[j].Port
+	})
+
+	addrs := make([]grpcresolver.Address, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		addrs = append(addrs, grpcresolver.Address{
+			Addr:       net.JoinHostPort(host, strconv.Itoa(int(srv.Port))),
+			ServerName: host,
+		})
+	}
+	return addrs, nil
+}
+
+// srvResolverBuilder builds gRPC resolvers for srv+dns targets.
+type srvResolverBuilder struct {
+	r       resolver
+	refresh time.Duration
+}
+
+// newSRVResolverBuilder returns the builder for c's srv+dns endpoint.
+func (c Config) newSRVResolverBuilder() *srvResolverBuilder {
+	refresh := c.DNSRefresh
+	if refresh == 0 {
+		refresh = defaultDNSRefresh
+	}
+	return &srvResolverBuilder{r: dnsResolver, refresh: refresh}
+}
+
+// Scheme implements resolver.Builder.
+func (b *srvResolverBuilder) Scheme() string {
+	return strings.TrimSuffix(srvScheme, "://")
+}
+
+// Build implements resolver.Builder.
+func (b *srvResolverBuilder) Build(target grpcresolver.Target, cc grpcresolver.ClientConn, _ grpcresolver.BuildOptions) (grpcresolver.Resolver, error) {
+	name := target.Endpoint()
+	if name == "" {
+		name = target.URL.Host
+	}
+	if name == "" {
+		return nil, fmt.Errorf("srv+dns target %q has no name to look up", target.URL.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sr := &srvResolver{
+		name:    name,
+		r:       b.r,
+		refresh: b.refresh,
+		cc:      cc,
+		ctx:     ctx,
+		cancel:  cancel,
+		now:     make(chan struct{}, 1),
+	}
+	sr.wg.Add(1)
+	go sr.watch()
+
+	return sr, nil
+}
+
+// srvResolver is a gRPC resolver that looks up an SRV name every refresh
+// interval and hands the targets to gRPC, so collectors can come and go
+// without the exporter being recreated.
+type srvResolver struct {
+	name    string
+	r       resolver
+	refresh time.Duration
+	cc      grpcresolver.ClientConn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	now    chan struct{}
+}
+
+// ResolveNow implements resolver.Resolver.
+func (sr *srvResolver) ResolveNow(grpcresolver.ResolveNowOptions) {
+	select {
+	case sr.now <- struct{}{}:
+	default:
+	}
+}
+
+// Close implements resolver.Resolver.
+func (sr *srvResolver) Close() {
+	sr.cancel()
+	sr.wg.Wait()
+	setResolved(sr.name, nil)
+}
+
+func (sr *srvResolver) watch() {
+	defer sr.wg.Done()
+
+	ticker := time.NewTicker(sr.refresh)
+	defer ticker.Stop()
+	for {
+		sr.resolve()
+
+		select {
+		case <-sr.ctx.Done():
+			return
+		case <-time.After(min(minResolveInterval, sr.refresh)):
+		}
+
+		select {
+		case <-sr.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-sr.now:
+		}
+	}
+}
+
+func (sr *srvResolver) resolve() {
+	ctx, cancel := context.WithTimeout(sr.ctx, 10*time.Second)
+	defer cancel()
+
+	addrs, err := lookupSRVAddresses(ctx, sr.r, sr.name)
+	if err != nil {
+		if sr.ctx.Err() == nil {
+			logger().Warn("could not look up OTLP collectors", "name", sr.name, "error", err)
+			sr.cc.ReportError(err)
+		}
+		return
+	}
+	setResolved(sr.name, addrs)
+	sr.cc.UpdateState(grpcresolver.State{Addresses: addrs})
+}
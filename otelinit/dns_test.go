@@ -0,0 +1,209 @@
+package otelinit
+
+import (
+	"context"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeDNS is a DNS server on a local UDP port that answers SRV queries from
+// a table, standing in for the real thing so lookups go over the wire.
+type fakeDNS struct {
+	addr string
+
+	mu   sync.Mutex
+	srvs map[string][]dnsmessage.SRVResource
+}
+
+func startFakeDNS(t *testing.T) *fakeDNS {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fake DNS could not listen: %s", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	d := &fakeDNS{addr: pc.LocalAddr().String(), srvs: map[string][]dnsmessage.SRVResource{}}
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := d.answer(buf[:n]); resp != nil {
+				pc.WriteTo(resp, from)
+			}
+		}
+	}()
+
+	return d
+}
+
+// answer builds the response to a query, or returns nil for garbage.
+func (d *fakeDNS) answer(query []byte) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil
+	}
+
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true, RecursionDesired: h.RecursionDesired},
+		Questions: []dnsmessage.Question{q},
+	}
+
+	d.mu.Lock()
+	srvs, ok := d.srvs[q.Name.String()]
+	d.mu.Unlock()
+
+	switch {
+	case !ok:
+		resp.RCode = dnsmessage.RCodeNameError
+	case q.Type == dnsmessage.TypeSRV:
+		for i := range srvs {
+			resp.Answers = append(resp.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 1},
+				Body:   &srvs[i],
+			})
+		}
+	}
+
+	packed, err := resp.Pack()
+	if err != nil {
+		return nil
+	}
+	return packed
+}
+
+// setSRV replaces the SRV records for name with one per host:port target.
+func (d *fakeDNS) setSRV(t *testing.T, name string, targets ...string) {
+	t.Helper()
+
+	var srvs []dnsmessage.SRVResource
+	for _, target := range targets {
+		host, port, err := net.SplitHostPort(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, _ := strconv.Atoi(port)
+		srvs = append(srvs, dnsmessage.SRVResource{
+			Priority: 10,
+			Weight:   10,
+			Port:     uint16(p),
+			Target:   dnsmessage.MustNewName(host + "."),
+		})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.srvs[name+"."] = srvs
+}
+
+// useFakeDNS points otelinit's lookups at d until the test is over.
+func useFakeDNS(t *testing.T, d *fakeDNS) {
+	prev := dnsResolver
+	dnsResolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var nd net.Dialer
+			return nd.DialContext(ctx, "udp", d.addr)
+		},
+	}
+	t.Cleanup(func() { dnsResolver = prev })
+}
+
+func TestSRVEndpointConfig(t *testing.T) {
+	// reading the config doesn't look anything up, so there is no DNS
+	// server here to answer
+	os.Clearenv()
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "srv+dns://_otlp._tcp.collector.test")
+	os.Setenv("OTEL_INIT_DNS_REFRESH", "10s")
+	defer os.Clearenv()
+
+	c, err := newConfig(testServiceName)
+	if err != nil {
+		t.Fatalf("newConfig failed: %s", err)
+	}
+
+	want := Config{
+		Servicename: testServiceName,
+		Endpoint:    "srv+dns://_otlp._tcp.collector.test",
+		DNSRefresh:  10 * time.Second,
+	}
+	if diff := cmp.Diff(want, c); diff != "" {
+		t.Errorf("unexpected config (-want +got):\n%s", diff)
+	}
+	if target := c.grpcTarget(); target != "srv+dns:///_otlp._tcp.collector.test" {
+		t.Errorf("unexpected gRPC target %q", target)
+	}
+}
+
+func TestSRVEndpointExport(t *testing.T) {
+	d := startFakeDNS(t)
+	useFakeDNS(t, d)
+
+	first := startFakeCollector(t, "tcp", "127.0.0.1:0")
+	second := startFakeCollector(t, "tcp", "127.0.0.1:0")
+	_, firstPort, _ := net.SplitHostPort(first.addr)
+	_, secondPort, _ := net.SplitHostPort(second.addr)
+	d.setSRV(t, "_otlp._tcp.collector.test", net.JoinHostPort("localhost", firstPort))
+
+	ctx := context.Background()
+	c := Config{
+		Servicename:   testServiceName,
+		Endpoint:      "srv+dns://_otlp._tcp.collector.test",
+		Insecure:      true,
+		RetryDisabled: true,
+		DNSRefresh:    50 * time.Millisecond,
+	}
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), options{})
+	defer func() {
+		shutdown(ctx)
+		clearFlushers()
+		if got := ResolvedEndpoints(); got != nil {
+			t.Errorf("expected no resolved endpoints after shutdown, got %v", got)
+		}
+	}()
+
+	_, span := otel.Tracer("test").Start(ctx, "first")
+	span.End()
+	if err := Flush(ctx); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+	if diff := cmp.Diff([]string{"first"}, first.spanNames()); diff != "" {
+		t.Fatalf("first collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+	want := map[string][]string{c.Endpoint: {net.JoinHostPort("localhost", firstPort)}}
+	if diff := cmp.Diff(want, ResolvedEndpoints()); diff != "" {
+		t.Errorf("unexpected resolved endpoints (-want +got):\n%s", diff)
+	}
+
+	// the record moves to the second collector, exports follow it once
+	// it's been looked up again
+	d.setSRV(t, "_otlp._tcp.collector.test", net.JoinHostPort("localhost", secondPort))
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(second.spanNames(), "second") {
+		if time.Now().After(deadline) {
+			t.Fatal("spans never reached the collector in the updated SRV record")
+		}
+		_, span := otel.Tracer("test").Start(ctx, "second")
+		span.End()
+		Flush(ctx)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
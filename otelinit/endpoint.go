@@ -0,0 +1,104 @@
+package otelinit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultOTLPPort is the standard port for OTLP/gRPC, used when an endpoint
+// doesn't specify one.
+const defaultOTLPPort = "4317"
+
+// validHostname is deliberately loose: it's only here to catch things that
+// are obviously not a hostname, like "asdf asdf asdf".
+var validHostname = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// isLocalEndpoint reports whether a normalized endpoint is on this machine:
+// a Unix socket, localhost, or a loopback IP. Hostnames other than localhost
+// are not resolved, so they always count as remote.
+func isLocalEndpoint(endpoint string) bool {
+	if strings.HasPrefix(endpoint, unixScheme) {
+		return true
+	}
+	if strings.HasPrefix(endpoint, srvScheme) {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseEndpoint validates an OTLP endpoint and normalizes it to the form the
+// gRPC exporter wants. It accepts host, host:port, http:// and https:// URLs,
+// unix:// socket paths, and srv+dns:// SRV names. The scheme is returned so
+// the caller can derive the insecure default from it the way the
+// OpenTelemetry spec describes.
+func parseEndpoint(raw string) (endpoint, scheme string, err error) {
+	if strings.HasPrefix(raw, unixScheme) {
+		if strings.TrimPrefix(raw, unixScheme) == "" {
+			return "", "", errors.New("unix endpoint is missing a socket path")
+		}
+		return raw, "unix", nil
+	}
+
+	if strings.HasPrefix(raw, srvScheme) {
+		name := strings.TrimSuffix(strings.TrimPrefix(raw, srvScheme), "/")
+		if name == "" {
+			return "", "", errors.New("srv+dns endpoint is missing a name to look up")
+		}
+		if !validHostname.MatchString(name) {
+			return "", "", fmt.Errorf("%q is not a valid SRV name", name)
+		}
+		return srvScheme + name, "srv+dns", nil
+	}
+
+	hostport := raw
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", "", err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return "", "", fmt.Errorf("unsupported scheme %q, try http, https, unix, or srv+dns", u.Scheme)
+		}
+		scheme = u.Scheme
+		hostport = u.Host
+	}
+
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// no port is fine, anything else that fails to split is not
+		var addrErr *net.AddrError
+		if !errors.As(err, &addrErr) || addrErr.Err != "missing port in address" {
+			return "", "", err
+		}
+		host, port = hostport, defaultOTLPPort
+		if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+			host = host[1 : len(host)-1] // a bracketed IPv6 literal
+		}
+	}
+
+	if host == "" {
+		return "", "", errors.New("missing host")
+	}
+	if net.ParseIP(host) == nil && !validHostname.MatchString(host) {
+		return "", "", fmt.Errorf("%q is not a valid hostname or IP address", host)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return "", "", fmt.Errorf("%q is not a valid port", port)
+	}
+
+	return net.JoinHostPort(host, port), scheme, nil
+}
@@ -0,0 +1,188 @@
+package otelinit
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDedupWindow is how long an identical error is suppressed after it has
+// been logged once. The exporters retry every batch, so without this a down
+// collector produces a log line every few seconds.
+const errorDedupWindow = time.Minute
+
+// ErrorStats holds counts of errors reported to the OpenTelemetry error
+// handler, by category. Suppressed counts the errors that were counted but
+// not logged because an identical error was logged recently.
+type ErrorStats struct {
+	Connection      uint64 `json:"connection"`
+	Auth            uint64 `json:"auth"`
+	PayloadTooLarge uint64 `json:"payload_too_large"`
+	Other           uint64 `json:"other"`
+	Suppressed      uint64 `json:"suppressed"`
+}
+
+// Total returns the number of errors counted across all categories.
+func (s ErrorStats) Total() uint64 {
+	return s.Connection + s.Auth + s.PayloadTooLarge + s.Other
+}
+
+// errHandler is the otel.ErrorHandler installed by InitOpenTelemetry.
+var errHandler = newErrorHandler()
+
+// Stats returns a snapshot of the error counts seen by the OpenTelemetry
+// error handler installed by otelinit.
+func Stats() ErrorStats {
+	return errHandler.stats()
+}
+
+// errorHandler is an otel.ErrorHandler that counts errors by category and
+// only logs each distinct error once per errorDedupWindow. Export errors
+// carry addresses and counts, so distinct messages keep turning up over
+// the life of a process; ones not seen for a window are forgotten.
+type errorHandler struct {
+	mu         sync.Mutex
+	counts     ErrorStats
+	lastLogged map[string]time.Time
+	lastSeen   map[string]time.Time
+	repeats    map[string]uint64
+	lastPruned time.Time
+	now        func() time.Time // for tests
+}
+
+func newErrorHandler() *errorHandler {
+	return &errorHandler{
+		lastLogged: make(map[string]time.Time),
+		lastSeen:   make(map[string]time.Time),
+		repeats:    make(map[string]uint64),
+		now:        time.Now,
+	}
+}
+
+// Handle implements otel.ErrorHandler.
+func (h *errorHandler) Handle(err error) {
+	if err == nil {
+		return
+	}
+
+	category := classifyError(err)
+	msg := err.Error()
+
+	h.mu.Lock()
+	switch category {
+	case "connection":
+		h.counts.Connection++
+	case "auth":
+		h.counts.Auth++
+	case "payload_too_large":
+		h.counts.PayloadTooLarge++
+	default:
+		h.counts.Other++
+	}
+
+	now := h.now()
+	h.prune(now)
+	h.lastSeen[msg] = now
+	if last, ok := h.lastLogged[msg]; ok && now.Sub(last) < errorDedupWindow {
+		h.counts.Suppressed++
+		h.repeats[msg]++
+		h.mu.Unlock()
+		return
+	}
+	repeated := h.repeats[msg]
+	h.lastLogged[msg] = now
+	delete(h.repeats, msg)
+	h.mu.Unlock()
+
+	if repeated > 0 {
+		logger().Error("OpenTelemetry error", "category", category, "error", err, "suppressed_repeats", repeated)
+	} else {
+		logger().Error("OpenTelemetry error", "category", category, "error", err)
+	}
+}
+
+// prune forgets the errors that have not been seen for a window, at most
+// once a window. An error that keeps recurring is never forgotten, so its
+// suppressed repeats are reported when it is next logged. Called with h.mu
+// held.
+func (h *errorHandler) prune(now time.Time) {
+	if now.Sub(h.lastPruned) < errorDedupWindow {
+		return
+	}
+	h.lastPruned = now
+
+	for msg, last := range h.lastSeen {
+		if now.Sub(last) >= errorDedupWindow {
+			delete(h.lastLogged, msg)
+			delete(h.lastSeen, msg)
+			delete(h.repeats, msg)
+		}
+	}
+}
+
+// stats returns a copy of the current counts.
+func (h *errorHandler) stats() ErrorStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counts
+}
+
+// logSummary writes the error counts to the diagnostic logger if there were
+// any errors. Called at shutdown.
+func (h *errorHandler) logSummary() {
+	s := h.stats()
+	if s.Total() == 0 {
+		return
+	}
+
+	logger().Warn("OpenTelemetry export errors during this run",
+		"connection", s.Connection,
+		"auth", s.Auth,
+		"payload_too_large", s.PayloadTooLarge,
+		"other", s.Other,
+		"suppressed", s.Suppressed,
+	)
+}
+
+// classifyError sorts an error into one of the ErrorStats categories, using
+// the gRPC status code when there is one and falling back to the text.
+func classifyError(err error) string {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		switch se.GRPCStatus().Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return "connection"
+		case codes.Unauthenticated, codes.PermissionDenied:
+			return "auth"
+		case codes.ResourceExhausted:
+			if isTooLarge(se.GRPCStatus().Message()) {
+				return "payload_too_large"
+			}
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case isTooLarge(msg):
+		return "payload_too_large"
+	case strings.Contains(msg, "unauthenticated"), strings.Contains(msg, "permission denied"):
+		return "auth"
+	case strings.Contains(msg, "connection refused"),
+		strings.Contains(msg, "no such host"),
+		strings.Contains(msg, "unavailable"),
+		strings.Contains(msg, "deadline exceeded"):
+		return "connection"
+	}
+
+	return "other"
+}
+
+// isTooLarge looks for the phrasings gRPC uses for oversized messages.
+func isTooLarge(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "larger than max") || strings.Contains(msg, "too large")
+}
@@ -0,0 +1,124 @@
+package otelinit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want string
+	}{
+		"grpc unavailable": {
+			err:  fmt.Errorf("traces export: %w", status.Error(codes.Unavailable, "connection error")),
+			want: "connection",
+		},
+		"grpc unauthenticated": {
+			err:  status.Error(codes.Unauthenticated, "bad token"),
+			want: "auth",
+		},
+		"grpc message too large": {
+			err:  status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5000000 vs. 4194304)"),
+			want: "payload_too_large",
+		},
+		"plain connection refused": {
+			err:  errors.New("dial tcp 127.0.0.1:4317: connect: connection refused"),
+			want: "connection",
+		},
+		"something else": {
+			err:  errors.New("the cat sat on the keyboard"),
+			want: "other",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := classifyError(tc.err); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerDedup(t *testing.T) {
+	now := time.Unix(0, 0)
+	h := newErrorHandler()
+	h.now = func() time.Time { return now }
+
+	down := status.Error(codes.Unavailable, "collector is down")
+	h.Handle(down) // logged
+	h.Handle(down) // suppressed
+	h.Handle(down) // suppressed
+	h.Handle(status.Error(codes.PermissionDenied, "nope"))
+
+	now = now.Add(errorDedupWindow)
+	h.Handle(down) // window passed, logged again
+
+	want := ErrorStats{Connection: 4, Auth: 1, Suppressed: 2}
+	if diff := cmp.Diff(want, h.stats()); diff != "" {
+		t.Errorf("error stats did not match (-want +got):\n%s", diff)
+	}
+	if h.stats().Total() != 5 {
+		t.Errorf("expected a total of 5 errors, got %d", h.stats().Total())
+	}
+}
+
+func TestErrorHandlerPrunes(t *testing.T) {
+	now := time.Unix(0, 0)
+	h := newErrorHandler()
+	h.now = func() time.Time { return now }
+
+	// a flapping collector, each error with a different address in it
+	for i := 0; i < 100; i++ {
+		h.Handle(fmt.Errorf("dial tcp 10.0.0.%d:4317: connection refused", i))
+	}
+
+	now = now.Add(errorDedupWindow)
+	h.Handle(errors.New("dial tcp 10.0.1.1:4317: connection refused"))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.lastSeen) != 1 || len(h.lastLogged) != 1 || len(h.repeats) != 0 {
+		t.Errorf("expected old errors to be forgotten, still tracking %d, %d and %d", len(h.lastSeen), len(h.lastLogged), len(h.repeats))
+	}
+}
+
+func TestErrorHandlerReportsRepeats(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer SetLogger(nil)
+
+	now := time.Unix(0, 0)
+	h := newErrorHandler()
+	h.now = func() time.Time { return now }
+
+	// a collector that stays down, with the exporter retrying every 5s
+	down := status.Error(codes.Unavailable, "collector is down")
+	for i := 0; i <= 30; i++ {
+		h.Handle(down)
+		now = now.Add(5 * time.Second)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, got %d:\n%s", len(lines), buf.String())
+	}
+	if strings.Contains(lines[0], "suppressed_repeats") {
+		t.Errorf("expected no repeats on the first line, got %q", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if !strings.Contains(line, "suppressed_repeats=11") {
+			t.Errorf("expected suppressed_repeats=11, got %q", line)
+		}
+	}
+}
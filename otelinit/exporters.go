@@ -0,0 +1,121 @@
+package otelinit
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultTracesFile is where the file exporter writes when
+// OTEL_INIT_TRACES_FILE is not set.
+const defaultTracesFile = "otel-traces.jsonl"
+
+// tracesExporters returns the names of the trace exporters to set up. Unset
+// means otlp, and none means no exporters at all.
+func (c Config) tracesExporters() []string {
+	switch {
+	case len(c.TracesExporters) == 0:
+		return []string{"otlp"}
+	case len(c.TracesExporters) == 1 && c.TracesExporters[0] == "none":
+		return nil
+	default:
+		return c.TracesExporters
+	}
+}
+
+// enabled reports whether there is anywhere to send telemetry. Only the otlp
+// exporter needs an endpoint.
+func (c Config) enabled() bool {
+	if c.Endpoint != "" || c.TraceRoutingDNS != "" {
+		return true
+	}
+	for _, name := range c.tracesExporters() {
+		if name != "otlp" {
+			return true
+		}
+	}
+	return false
+}
+
+// newSpanExporter creates the trace exporter with the given name, see
+// OTEL_TRACES_EXPORTER. It returns a nil exporter and no error when the
+// exporter can't be used with this config.
+func (c Config) newSpanExporter(ctx context.Context, name string, o options) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "otlp":
+		if c.Endpoint == "" && c.TraceRoutingDNS == "" {
+			logger().Warn("otlp trace exporter has no endpoint, skipping it", "env", "OTEL_TRACES_EXPORTER")
+			return nil, nil
+		}
+		if c.TraceRouting == "traceid" {
+			return c.newTraceIDExporter(ctx, o)
+		}
+		if len(c.Endpoints) > 1 {
+			return c.newFailoverExporter(ctx, o)
+		}
+		return c.newTraceExporter(ctx, o)
+	case "console":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		return c.newFileExporter()
+	case "buffer":
+		return newLazyExporter(o), nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", name)
+	}
+}
+
+// fileExporter writes spans as JSON lines to a file, and closes the file at
+// shutdown.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	f *os.File
+}
+
+// newFileExporter opens the traces file for appending.
+func (c Config) newFileExporter() (*fileExporter, error) {
+	path := c.TracesFile
+	if path == "" {
+		path = defaultTracesFile
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open traces file: %w", err)
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &fileExporter{Exporter: exporter, f: f}, nil
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (fe *fileExporter) Shutdown(ctx context.Context) error {
+	err := fe.Exporter.Shutdown(ctx)
+	if cerr := fe.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// namedExporter tags a SpanExporter's errors with its name, so a failing
+// exporter can be told apart from the others it's fanned out with.
+type namedExporter struct {
+	name string
+	sdktrace.SpanExporter
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (ne namedExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if err := ne.SpanExporter.ExportSpans(ctx, spans); err != nil {
+		return fmt.Errorf("%s trace exporter: %w", ne.name, err)
+	}
+	return nil
+}
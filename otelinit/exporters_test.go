@@ -0,0 +1,88 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+)
+
+func TestTracesExporterFanOut(t *testing.T) {
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	ctx := context.Background()
+	c := Config{
+		Servicename:     testServiceName,
+		Endpoint:        fc.addr,
+		Insecure:        true,
+		TracesExporters: []string{"otlp", "file"},
+		TracesFile:      path,
+	}
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), options{})
+
+	_, span := otel.Tracer("test").Start(ctx, "fanned out")
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+	clearFlushers()
+
+	if diff := cmp.Diff([]string{"fanned out"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read traces file: %s", err)
+	}
+	if !strings.Contains(string(data), `"Name":"fanned out"`) {
+		t.Errorf("expected the span in the traces file, got %q", data)
+	}
+}
+
+func TestTracesExporterNoEndpoint(t *testing.T) {
+	tests := map[string]struct {
+		c    Config
+		want bool
+	}{
+		"default needs an endpoint": {
+			c:    Config{},
+			want: false,
+		},
+		"otlp needs an endpoint": {
+			c:    Config{TracesExporters: []string{"otlp"}},
+			want: false,
+		},
+		"console works without one": {
+			c:    Config{TracesExporters: []string{"otlp", "console"}},
+			want: true,
+		},
+		"none with an endpoint is still enabled": {
+			c:    Config{Endpoint: "localhost:4317", TracesExporters: []string{"none"}},
+			want: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.c.enabled(); got != tc.want {
+				t.Errorf("expected enabled to be %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNamedExporterErrors(t *testing.T) {
+	ne := namedExporter{name: "file", SpanExporter: &stubExporter{err: errors.New("disk full")}}
+
+	err := ne.ExportSpans(context.Background(), nil)
+	if err == nil || err.Error() != "file trace exporter: disk full" {
+		t.Errorf("expected the error to carry the exporter name, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// failoverRecheckInterval is how long an endpoint that failed is skipped
+// before it is tried again. Endpoints earlier in the list are preferred, so
+// once the primary comes back, exports switch back to it.
+const failoverRecheckInterval = 30 * time.Second
+
+// EndpointStatus describes one of the endpoints configured with
+// OTEL_INIT_ENDPOINTS.
+type EndpointStatus struct {
+	Endpoint string `json:"endpoint"`
+	// Active is true for the endpoint the last successful export went to.
+	Active bool `json:"active"`
+	// Healthy is false when the last export to the endpoint failed.
+	Healthy     bool      `json:"healthy"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// currentFailover is the failover exporter set up by InitOpenTelemetry, if
+// any, for EndpointStatuses.
+var currentFailover atomic.Pointer[failoverExporter]
+
+// EndpointStatuses reports the health of each endpoint in OTEL_INIT_ENDPOINTS
+// and which one is active, in order of preference. Returns nil when failover
+// is not in use.
+func EndpointStatuses() []EndpointStatus {
+	if f := currentFailover.Load(); f != nil {
+		return f.statuses()
+	}
+	return nil
+}
+
+// failoverExporter is a SpanExporter that sends each batch to the most
+// preferred endpoint that hasn't failed recently, moving down the list when
+// an export fails.
+type failoverExporter struct {
+	endpoints []string
+	exporters []sdktrace.SpanExporter
+	recheck   time.Duration
+	now       func() time.Time // for tests
+
+	mu       sync.Mutex
+	active   int
+	failedAt []time.Time
+	lastErr  []error
+}
+
+// forEndpoint returns the config for exporting to just one of c.Endpoints,
+// with the proxy worked out for that endpoint rather than the first one.
+func (c Config) forEndpoint(endpoint string) Config {
+	c.Endpoint, c.Endpoints = endpoint, nil
+	c.Proxy = proxyForEndpoint(endpoint)
+	return c
+}
+
+// newFailoverExporter creates an OTLP exporter for each of c.Endpoints and
+// wraps them in a failoverExporter. The exporters don't retry, since a
+// retrying exporter for a dead endpoint would use up the whole export
+// deadline before the next endpoint got a chance. Moving down the list is
+// the retry.
+func (c Config) newFailoverExporter(ctx context.Context, o options) (*failoverExporter, error) {
+	exporters := make([]sdktrace.SpanExporter, 0, len(c.Endpoints))
+	for _, ep := range c.Endpoints {
+		ec := c.forEndpoint(ep)
+		ec.RetryDisabled = true
+		exporter, err := ec.newTraceExporter(ctx, o)
+		if err != nil {
+			errs := []error{fmt.Errorf("could not create exporter for %q: %w", ep, err)}
+			for _, created := range exporters {
+				errs = append(errs, created.Shutdown(ctx))
+			}
+			return nil, errors.Join(errs...)
+		}
+		exporters = append(exporters, exporter)
+	}
+
+	f := newFailover(c.Endpoints, exporters)
+	currentFailover.Store(f)
+	return f, nil
+}
+
+func newFailover(endpoints []string, exporters []sdktrace.SpanExporter) *failoverExporter {
+	return &failoverExporter{
+		endpoints: endpoints,
+		exporters: exporters,
+		recheck:   failoverRecheckInterval,
+		now:       time.Now,
+		failedAt:  make([]time.Time, len(exporters)),
+		lastErr:   make([]error, len(exporters)),
+	}
+}
+
+// ExportSpans implements sdktrace.SpanExporter. Endpoints that failed within
+// the recheck interval are tried last, after all the others. When ctx has a
+// deadline, each attempt gets an even share of the time left so that an
+// endpoint that hangs can't starve the ones after it.
+func (f *failoverExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	var errs []error
+	order := f.order()
+	for n, i := range order {
+		err := f.export(ctx, i, spans, len(order)-n)
+		f.record(i, err)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("export to %q failed: %w", f.endpoints[i], err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// export sends spans to exporter i, giving it 1/left of the time remaining
+// before ctx's deadline.
+func (f *failoverExporter) export(ctx context.Context, i int, spans []sdktrace.ReadOnlySpan, left int) error {
+	if deadline, ok := ctx.Deadline(); ok && left > 1 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Until(deadline)/time.Duration(left))
+		defer cancel()
+	}
+	return f.exporters[i].ExportSpans(ctx, spans)
+}
+
+// order returns the indexes of the exporters in the order to try them:
+// preferred endpoints that haven't failed recently, then the rest.
+func (f *failoverExporter) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	var fresh, failed []int
+	for i, at := range f.failedAt {
+		if at.IsZero() || now.Sub(at) >= f.recheck {
+			fresh = append(fresh, i)
+		} else {
+			failed = append(failed, i)
+		}
+	}
+	return append(fresh, failed...)
+}
+
+// record updates the health of exporter i after an export.
+func (f *failoverExporter) record(i int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err != nil {
+		f.failedAt[i], f.lastErr[i] = f.now(), err
+		return
+	}
+
+	f.failedAt[i], f.lastErr[i] = time.Time{}, nil
+	if f.active != i {
+		logger().Info("switched OTLP endpoint", "from", f.endpoints[f.active], "to", f.endpoints[i])
+		f.active = i
+	}
+}
+
+// statuses returns a snapshot of every endpoint's health.
+func (f *failoverExporter) statuses() []EndpointStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]EndpointStatus, len(f.endpoints))
+	for i, ep := range f.endpoints {
+		out[i] = EndpointStatus{
+			Endpoint:    ep,
+			Active:      i == f.active,
+			Healthy:     f.lastErr[i] == nil,
+			LastFailure: f.failedAt[i],
+		}
+		if f.lastErr[i] != nil {
+			out[i].LastError = f.lastErr[i].Error()
+		}
+	}
+	return out
+}
+
+// Shutdown implements sdktrace.SpanExporter by shutting down every
+// endpoint's exporter.
+func (f *failoverExporter) Shutdown(ctx context.Context) error {
+	currentFailover.CompareAndSwap(f, nil)
+
+	var errs []error
+	for i, exporter := range f.exporters {
+		if err := exporter.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", f.endpoints[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
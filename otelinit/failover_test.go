@@ -0,0 +1,236 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubExporter is a SpanExporter that fails while err is set and counts the
+// batches it accepted.
+type stubExporter struct {
+	err      error
+	exported int
+	shutdown bool
+}
+
+func (s *stubExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.exported++
+	return nil
+}
+
+func (s *stubExporter) Shutdown(ctx context.Context) error {
+	s.shutdown = true
+	return nil
+}
+
+func TestFailoverSwitchback(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := &stubExporter{}, &stubExporter{}
+	f := newFailover([]string{"primary:4317", "secondary:4317"}, []sdktrace.SpanExporter{primary, secondary})
+
+	now := time.Unix(1700000000, 0)
+	f.now = func() time.Time { return now }
+
+	export := func() {
+		t.Helper()
+		if err := f.ExportSpans(ctx, nil); err != nil {
+			t.Fatalf("export failed: %s", err)
+		}
+	}
+
+	export()
+	if primary.exported != 1 || secondary.exported != 0 {
+		t.Fatalf("expected the first export to go to the primary, got %d/%d", primary.exported, secondary.exported)
+	}
+
+	// the primary goes down, exports move to the secondary
+	primary.err = errors.New("connection refused")
+	export()
+	if secondary.exported != 1 {
+		t.Fatalf("expected failover to the secondary, got %d", secondary.exported)
+	}
+	status := f.statuses()
+	if status[0].Active || status[0].Healthy || !status[1].Active {
+		t.Errorf("unexpected status after failover: %+v", status)
+	}
+
+	// the primary is not retried within the recheck interval
+	primary.err = nil
+	now = now.Add(f.recheck / 2)
+	export()
+	if primary.exported != 1 || secondary.exported != 2 {
+		t.Fatalf("expected the primary to be skipped while recent, got %d/%d", primary.exported, secondary.exported)
+	}
+
+	// once the interval has passed it is tried, and exports switch back
+	now = now.Add(f.recheck)
+	export()
+	if primary.exported != 2 {
+		t.Fatalf("expected a switch back to the primary, got %d", primary.exported)
+	}
+
+	want := []EndpointStatus{
+		{Endpoint: "primary:4317", Active: true, Healthy: true},
+		{Endpoint: "secondary:4317", Healthy: true},
+	}
+	if diff := cmp.Diff(want, f.statuses()); diff != "" {
+		t.Errorf("unexpected status after switchback (-want +got):\n%s", diff)
+	}
+}
+
+// hangingExporter is a SpanExporter that never answers, like a collector
+// whose packets are dropped.
+type hangingExporter struct{}
+
+func (hangingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (hangingExporter) Shutdown(ctx context.Context) error { return nil }
+
+func TestFailoverHungPrimary(t *testing.T) {
+	secondary := &stubExporter{}
+	f := newFailover([]string{"primary:4317", "secondary:4317"}, []sdktrace.SpanExporter{hangingExporter{}, secondary})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := f.ExportSpans(ctx, nil); err != nil {
+		t.Fatalf("expected the secondary to take the export, got %s", err)
+	}
+	if secondary.exported != 1 {
+		t.Errorf("expected the secondary to get the batch, got %d", secondary.exported)
+	}
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	f := newFailover(
+		[]string{"primary:4317", "secondary:4317"},
+		[]sdktrace.SpanExporter{&stubExporter{err: errors.New("down")}, &stubExporter{err: errors.New("also down")}},
+	)
+
+	err := f.ExportSpans(context.Background(), nil)
+	if err == nil {
+		t.Fatal("expected an error when every endpoint fails")
+	}
+	for _, s := range f.statuses() {
+		if s.Healthy || s.LastError == "" {
+			t.Errorf("expected %s to be unhealthy with an error, got %+v", s.Endpoint, s)
+		}
+	}
+}
+
+func TestFailoverExport(t *testing.T) {
+	// nothing listens on the primary
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := lis.Addr().String()
+	lis.Close()
+
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	ctx := context.Background()
+	c := Config{
+		Servicename:   testServiceName,
+		Endpoint:      deadAddr,
+		Endpoints:     []string{deadAddr, fc.addr},
+		Insecure:      true,
+		RetryDisabled: true,
+	}
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), options{})
+
+	_, span := otel.Tracer("test").Start(ctx, "failed over")
+	span.End()
+	if err := Flush(ctx); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+
+	status := EndpointStatuses()
+	if len(status) != 2 || status[0].Healthy || !status[1].Active {
+		t.Errorf("expected the second endpoint to be active, got %+v", status)
+	}
+
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+	clearFlushers()
+
+	if diff := cmp.Diff([]string{"failed over"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+	if status := EndpointStatuses(); status != nil {
+		t.Errorf("expected no status after shutdown, got %+v", status)
+	}
+}
+
+func TestFailoverDeadPrimaryRetries(t *testing.T) {
+	// nothing listens on the primary
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := lis.Addr().String()
+	lis.Close()
+
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	// retries are left at their defaults, which would keep trying the
+	// primary for longer than the export deadline
+	c := Config{
+		Servicename: testServiceName,
+		Endpoint:    deadAddr,
+		Endpoints:   []string{deadAddr, fc.addr},
+		Insecure:    true,
+	}
+	f, err := c.newFailoverExporter(context.Background(), options{})
+	if err != nil {
+		t.Fatalf("could not create failover exporter: %s", err)
+	}
+	defer f.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := f.ExportSpans(ctx, tracetest.SpanStubs{{Name: "failed over"}}.Snapshots()); err != nil {
+		t.Fatalf("export failed: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"failed over"}, fc.spanNames()); diff != "" {
+		t.Errorf("secondary did not receive the expected spans (-want +got):\n%s", diff)
+	}
+}
+
+func TestForEndpointProxy(t *testing.T) {
+	t.Setenv("OTEL_INIT_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+	t.Setenv("NO_PROXY", "secondary.internal")
+
+	c := Config{
+		Endpoint:  "primary.example.com:4317",
+		Endpoints: []string{"primary.example.com:4317", "secondary.internal:4317"},
+		Proxy:     "http://proxy.example.com:3128",
+	}
+
+	if got := c.forEndpoint("primary.example.com:4317").Proxy; got != "http://proxy.example.com:3128" {
+		t.Errorf("expected the primary to use the proxy, got %q", got)
+	}
+	ec := c.forEndpoint("secondary.internal:4317")
+	if ec.Proxy != "" {
+		t.Errorf("expected NO_PROXY to apply to the secondary, got proxy %q", ec.Proxy)
+	}
+	if ec.Endpoint != "secondary.internal:4317" || ec.Endpoints != nil {
+		t.Errorf("unexpected endpoints in per-endpoint config: %q %q", ec.Endpoint, ec.Endpoints)
+	}
+}
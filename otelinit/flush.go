@@ -0,0 +1,47 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flushers holds the ForceFlush funcs of every provider set up by
+// InitOpenTelemetry so Flush can reach them without the caller holding
+// references to the providers.
+var flushers struct {
+	sync.Mutex
+	fns []func(context.Context) error
+}
+
+// registerFlusher adds a provider's ForceFlush to the set called by Flush.
+func registerFlusher(fn func(context.Context) error) {
+	flushers.Lock()
+	defer flushers.Unlock()
+	flushers.fns = append(flushers.fns, fn)
+}
+
+// clearFlushers forgets all registered providers. Called at shutdown.
+func clearFlushers() {
+	flushers.Lock()
+	defer flushers.Unlock()
+	flushers.fns = nil
+}
+
+// Flush exports everything buffered in the OpenTelemetry providers without
+// shutting them down. This is mainly useful for short-lived programs that
+// are about to call os.Exit and won't run their deferred shutdown. Returns
+// the errors from all providers joined together, or nil when otelinit is
+// inert.
+func Flush(ctx context.Context) error {
+	flushers.Lock()
+	fns := append([]func(context.Context) error{}, flushers.fns...)
+	flushers.Unlock()
+
+	var errs []error
+	for _, fn := range fns {
+		errs = append(errs, fn(ctx))
+	}
+
+	return errors.Join(errs...)
+}
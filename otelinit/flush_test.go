@@ -0,0 +1,33 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFlush(t *testing.T) {
+	defer clearFlushers()
+
+	if err := Flush(context.Background()); err != nil {
+		t.Errorf("expected nil error with nothing registered, got %s", err)
+	}
+
+	var calls int
+	boom := errors.New("boom")
+	registerFlusher(func(context.Context) error { calls++; return nil })
+	registerFlusher(func(context.Context) error { calls++; return boom })
+
+	err := Flush(context.Background())
+	if !errors.Is(err, boom) {
+		t.Errorf("expected flush error to wrap %q, got %v", boom, err)
+	}
+	if calls != 2 {
+		t.Errorf("expected every flusher to be called once, got %d calls", calls)
+	}
+
+	clearFlushers()
+	if err := Flush(context.Background()); err != nil {
+		t.Errorf("expected nil error after clearing flushers, got %s", err)
+	}
+}
@@ -0,0 +1,168 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultLazyBufferSize is how many spans are held for AttachExporter when
+// WithBufferUntilAttached is given a size of zero or less.
+const defaultLazyBufferSize = 2048
+
+// lazyDrainBatchSize is how many buffered spans go to the real exporter at
+// a time when draining, the batch span processor's default.
+const lazyDrainBatchSize = 512
+
+// currentLazy is the buffering exporter set up by InitOpenTelemetry, if
+// any, for AttachExporter.
+var currentLazy atomic.Pointer[lazyExporter]
+
+// WithBufferUntilAttached makes InitOpenTelemetry start tracing even when no
+// endpoint is configured, holding on to up to maxSpans spans in memory until
+// AttachExporter supplies one. This is for programs that only learn where
+// their collector is after startup. When the buffer is full the oldest spans
+// are dropped. Has no effect when an endpoint is configured.
+func WithBufferUntilAttached(maxSpans int) Option {
+	return func(o *options) {
+		if maxSpans <= 0 {
+			maxSpans = defaultLazyBufferSize
+		}
+		o.lazyBufferSize = maxSpans
+	}
+}
+
+// AttachExporter creates the OTLP trace exporter for c, sends it the spans
+// buffered since InitOpenTelemetry, and sends all spans after that to it
+// directly. It only works once, and only when InitOpenTelemetry was called
+// with WithBufferUntilAttached and had no endpoint. The endpoints in c are
+// checked the same way as ones from the environment: an http:// endpoint
+// means plaintext, an empty Proxy comes from HTTPS_PROXY and NO_PROXY, and
+// plaintext to a remote endpoint needs AllowInsecureRemote. The returned
+// error is also non-nil when some buffered spans could not be exported, but
+// the exporter is attached anyway.
+func AttachExporter(ctx context.Context, c Config) error {
+	le := currentLazy.Load()
+	if le == nil {
+		return errors.New("not buffering spans, call InitOpenTelemetry with WithBufferUntilAttached and no endpoint first")
+	}
+	if le.attached() {
+		return errAlreadyAttached
+	}
+	if c.Endpoint == "" && c.TraceRoutingDNS == "" {
+		return errors.New("config has no endpoint to attach")
+	}
+	c, err := c.checkEndpoints()
+	if err != nil {
+		return err
+	}
+
+	exporter, err := c.newSpanExporter(ctx, "otlp", le.o)
+	if err != nil {
+		return fmt.Errorf("could not create OTLP exporter: %w", err)
+	}
+
+	err = le.attach(ctx, exporter)
+	if errors.Is(err, errAlreadyAttached) {
+		// lost a race with another AttachExporter, so nothing else will
+		// ever shut this one down
+		return errors.Join(err, exporter.Shutdown(ctx))
+	}
+	return err
+}
+
+// errAlreadyAttached is returned by AttachExporter after the first call.
+var errAlreadyAttached = errors.New("an exporter is already attached")
+
+// lazyExporter is a SpanExporter that buffers spans until the real exporter
+// is attached, then passes everything through to it.
+type lazyExporter struct {
+	max int
+	o   options
+
+	mu      sync.Mutex
+	buf     []sdktrace.ReadOnlySpan
+	dropped uint64
+	target  sdktrace.SpanExporter
+}
+
+func newLazyExporter(o options) *lazyExporter {
+	le := &lazyExporter{max: o.lazyBufferSize, o: o}
+	currentLazy.Store(le)
+	return le
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (le *lazyExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	le.mu.Lock()
+	if target := le.target; target != nil {
+		le.mu.Unlock()
+		return target.ExportSpans(ctx, spans)
+	}
+	defer le.mu.Unlock()
+
+	le.buf = append(le.buf, spans...)
+	if over := len(le.buf) - le.max; over > 0 {
+		le.buf = append(le.buf[:0:0], le.buf[over:]...)
+		le.dropped += uint64(over)
+	}
+	return nil
+}
+
+// attach drains the buffer into target and makes it the exporter for all
+// later spans. The lock is held while draining so later batches can't
+// overtake the buffered ones.
+func (le *lazyExporter) attach(ctx context.Context, target sdktrace.SpanExporter) error {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	if le.target != nil {
+		return errAlreadyAttached
+	}
+
+	if le.dropped > 0 {
+		logger().Warn("span buffer overflowed before an exporter was attached", "dropped", le.dropped)
+	}
+
+	var errs []error
+	for start := 0; start < len(le.buf); start += lazyDrainBatchSize {
+		end := min(start+lazyDrainBatchSize, len(le.buf))
+		if err := target.ExportSpans(ctx, le.buf[start:end]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	le.buf, le.target = nil, target
+	return errors.Join(errs...)
+}
+
+// attached reports whether an exporter has been attached yet.
+func (le *lazyExporter) attached() bool {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.target != nil
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (le *lazyExporter) Shutdown(ctx context.Context) error {
+	currentLazy.CompareAndSwap(le, nil)
+
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	if le.target == nil {
+		if len(le.buf) > 0 {
+			logger().Warn("no exporter was attached, buffered spans are lost", "spans", len(le.buf))
+		}
+		le.buf = nil
+		return nil
+	}
+	return le.target.Shutdown(ctx)
+}
+
+// make sure lazyExporter stays a SpanExporter
+var _ sdktrace.SpanExporter = (*lazyExporter)(nil)
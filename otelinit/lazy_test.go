@@ -0,0 +1,153 @@
+package otelinit
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAttachExporter(t *testing.T) {
+	os.Clearenv()
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	ctx, shutdown := InitOpenTelemetry(context.Background(), testServiceName, WithBufferUntilAttached(10))
+
+	_, span := otel.Tracer("test").Start(ctx, "before")
+	span.End()
+	if err := Flush(ctx); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+
+	err := AttachExporter(ctx, Config{Servicename: testServiceName, Endpoint: fc.addr, Insecure: true})
+	if err != nil {
+		t.Fatalf("attach failed: %s", err)
+	}
+	if err := AttachExporter(ctx, Config{Endpoint: fc.addr, Insecure: true}); err == nil {
+		t.Error("expected attaching a second time to fail")
+	}
+
+	_, span = otel.Tracer("test").Start(ctx, "after")
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"before", "after"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+	if err := AttachExporter(ctx, Config{Endpoint: fc.addr, Insecure: true}); err == nil {
+		t.Error("expected attaching after shutdown to fail")
+	}
+}
+
+func TestAttachExporterChecksEndpoint(t *testing.T) {
+	os.Clearenv()
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	ctx, shutdown := InitOpenTelemetry(context.Background(), testServiceName, WithBufferUntilAttached(10))
+	defer shutdown(ctx)
+
+	bad := map[string]Config{
+		"invalid endpoint":    {Endpoint: "asdf asdf asdf"},
+		"plaintext to remote": {Endpoint: "collector.example.com:4317", Insecure: true},
+		"plaintext failover":  {Endpoint: fc.addr, Endpoints: []string{fc.addr, "collector.example.com"}, Insecure: true},
+	}
+	for name, c := range bad {
+		if err := AttachExporter(ctx, c); err == nil {
+			t.Errorf("%s: expected AttachExporter to refuse the config", name)
+		}
+	}
+
+	// still buffering, so a good config can be attached after all that
+	if err := AttachExporter(ctx, Config{Endpoint: "http://" + fc.addr, Insecure: true}); err != nil {
+		t.Fatalf("attach failed: %s", err)
+	}
+}
+
+func TestCheckEndpoints(t *testing.T) {
+	c, err := Config{
+		Endpoint:            "https://collector.example.com",
+		Endpoints:           []string{"https://collector.example.com", "backup.example.com:14317"},
+		Insecure:            true,
+		AllowInsecureRemote: true,
+	}.checkEndpoints()
+	if err != nil {
+		t.Fatalf("expected the config to be accepted, got %s", err)
+	}
+	if diff := cmp.Diff([]string{"collector.example.com:4317", "backup.example.com:14317"}, c.Endpoints); diff != "" {
+		t.Errorf("unexpected endpoints (-want +got):\n%s", diff)
+	}
+	if c.Endpoint != "collector.example.com:4317" {
+		t.Errorf("expected a normalized endpoint, got %q", c.Endpoint)
+	}
+}
+
+func TestCheckEndpointsDefaults(t *testing.T) {
+	t.Setenv("OTEL_INIT_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+	t.Setenv("NO_PROXY", "")
+
+	c, err := Config{Endpoint: "http://localhost:4317"}.checkEndpoints()
+	if err != nil {
+		t.Fatalf("expected the config to be accepted, got %s", err)
+	}
+	if !c.Insecure {
+		t.Error("expected an http:// endpoint to turn on Insecure")
+	}
+
+	c, err = Config{Endpoint: "collector.example.com"}.checkEndpoints()
+	if err != nil {
+		t.Fatalf("expected the config to be accepted, got %s", err)
+	}
+	if c.Insecure {
+		t.Error("expected a bare endpoint to stay on TLS")
+	}
+	if c.Proxy != "http://proxy.example.com:3128" {
+		t.Errorf("expected the proxy from HTTPS_PROXY, got %q", c.Proxy)
+	}
+
+	c, err = Config{Endpoint: "collector.example.com", Proxy: "http://other.example.com:3128"}.checkEndpoints()
+	if err != nil {
+		t.Fatalf("expected the config to be accepted, got %s", err)
+	}
+	if c.Proxy != "http://other.example.com:3128" {
+		t.Errorf("expected the caller's proxy to be kept, got %q", c.Proxy)
+	}
+}
+
+func TestLazyExporterOverflow(t *testing.T) {
+	le := &lazyExporter{max: 2}
+
+	stubs := tracetest.SpanStubs{{Name: "one"}, {Name: "two"}, {Name: "three"}}
+	if err := le.ExportSpans(context.Background(), stubs.Snapshots()); err != nil {
+		t.Fatalf("buffering failed: %s", err)
+	}
+
+	recorder := tracetest.NewInMemoryExporter()
+	if err := le.attach(context.Background(), recorder); err != nil {
+		t.Fatalf("attach failed: %s", err)
+	}
+
+	var names []string
+	for _, s := range recorder.GetSpans() {
+		names = append(names, s.Name)
+	}
+	if diff := cmp.Diff([]string{"two", "three"}, names); diff != "" {
+		t.Errorf("expected the oldest span to be dropped (-want +got):\n%s", diff)
+	}
+	if le.dropped != 1 {
+		t.Errorf("expected 1 dropped span, got %d", le.dropped)
+	}
+
+	// later spans go straight through
+	if err := le.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "four"}}.Snapshots()); err != nil {
+		t.Fatalf("export failed: %s", err)
+	}
+	if n := len(recorder.GetSpans()); n != 3 {
+		t.Errorf("expected 3 spans after attaching, got %d", n)
+	}
+}
@@ -0,0 +1,45 @@
+package otelinit
+
+import (
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+)
+
+// diagLogger is where otelinit reports its own problems. When unset, the
+// slog default logger is used, which writes through the stdlib log package.
+var diagLogger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger otelinit uses for its own diagnostics and also
+// installs it as the OpenTelemetry global logger. To silence otelinit, pass
+// a logger with a handler that discards everything. Passing nil goes back to
+// slog.Default() for both.
+func SetLogger(l *slog.Logger) {
+	diagLogger.Store(l)
+	otel.SetLogger(logr.FromSlogHandler(logger().Handler()))
+}
+
+// WithLogger is an Option that calls SetLogger before anything else happens
+// in InitOpenTelemetry, so configuration problems are reported through it.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// logger returns the current diagnostic logger.
+func logger() *slog.Logger {
+	if l := diagLogger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// fatal logs at error level and exits the process, same as log.Fatal would.
+func fatal(msg string, args ...any) {
+	logger().Error(msg, args...)
+	os.Exit(1)
+}
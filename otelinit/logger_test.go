@@ -0,0 +1,31 @@
+package otelinit
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer SetLogger(nil)
+
+	os.Clearenv()
+	os.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "maybe")
+	newConfig(testServiceName)
+
+	out := buf.String()
+	for _, want := range []string{"level=WARN", "env=OTEL_EXPORTER_OTLP_INSECURE", "value=maybe"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in diagnostic output, got %q", want, out)
+		}
+	}
+
+	SetLogger(nil)
+	if logger() != slog.Default() {
+		t.Error("expected SetLogger(nil) to go back to slog.Default()")
+	}
+}
@@ -0,0 +1,137 @@
+package otelinit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	otlploggrpc "go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// stdlibLogScope is the instrumentation scope name for records that come
+// from the standard library log package.
+const stdlibLogScope = "github.com/equinix-labs/otel-init-go/otelinit/stdlib"
+
+// installedStdlibWriter is the writer installed via log.SetOutput, if any.
+// LogWithContext uses it to build context-aware loggers.
+var installedStdlibWriter atomic.Pointer[stdlibLogWriter]
+
+func (c Config) initLogs(ctx context.Context, res *resource.Resource, o options) (context.Context, OtelShutdown) {
+	grpcOpts := []otlploggrpc.Option{
+		otlploggrpc.WithEndpoint(c.grpcTarget()),
+		otlploggrpc.WithTLSCredentials(c.transportCredentials()),
+		otlploggrpc.WithDialOption(c.dialOptions(o.dialOptions...)...),
+	}
+
+	if c.Timeout > 0 {
+		grpcOpts = append(grpcOpts, otlploggrpc.WithTimeout(c.Timeout))
+	}
+	if c.Compression == "gzip" {
+		grpcOpts = append(grpcOpts, otlploggrpc.WithCompressor(c.Compression))
+	}
+	grpcOpts = append(grpcOpts, otlploggrpc.WithRetry(otlploggrpc.RetryConfig(c.retryConfig())))
+
+	exporter, err := otlploggrpc.New(ctx, grpcOpts...)
+	if err != nil {
+		fatal("failed to configure OTLP log exporter", "endpoint", c.Endpoint, "error", err)
+	}
+
+	loggerProvider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+	)
+	global.SetLoggerProvider(loggerProvider)
+	registerFlusher(loggerProvider.ForceFlush)
+
+	// tee the stdlib logger: keep writing where it was writing before and
+	// also send every line to OTLP
+	orig := log.Writer()
+	w := &stdlibLogWriter{
+		ctx:    context.Background(),
+		next:   orig,
+		logger: loggerProvider.Logger(stdlibLogScope),
+	}
+	log.SetOutput(w)
+	installedStdlibWriter.Store(w)
+
+	return ctx, func(ctx context.Context) error {
+		// put things back first so nothing logs into a closed provider
+		installedStdlibWriter.Store(nil)
+		log.SetOutput(orig)
+
+		err := loggerProvider.Shutdown(ctx)
+		if err != nil {
+			return fmt.Errorf("shutdown of OpenTelemetry loggerProvider failed: %w", err)
+		}
+		return nil
+	}
+}
+
+// LogWithContext returns a *log.Logger that writes to the same places as the
+// standard library's default logger but attaches ctx to the emitted OTLP log
+// records, so they are correlated with the span in ctx. When WithStdlibLog
+// is not in effect, the default logger is returned.
+func LogWithContext(ctx context.Context) *log.Logger {
+	w := installedStdlibWriter.Load()
+	if w == nil {
+		return log.Default()
+	}
+
+	cw := *w
+	cw.ctx = ctx
+	return log.New(&cw, log.Prefix(), log.Flags())
+}
+
+// stdlibLogWriter is an io.Writer for log.SetOutput that writes each line to
+// the original destination and emits it as an OpenTelemetry log record.
+type stdlibLogWriter struct {
+	ctx    context.Context
+	next   io.Writer
+	logger otellog.Logger
+}
+
+// Write implements io.Writer. The stdlib log package calls Write once per
+// line so there's no need to buffer partial lines.
+func (w *stdlibLogWriter) Write(p []byte) (int, error) {
+	n, err := w.next.Write(p)
+
+	line := string(bytes.TrimRight(p, "\n"))
+	severity, text := inferSeverity(line)
+
+	var rec otellog.Record
+	rec.SetTimestamp(time.Now())
+	rec.SetBody(otellog.StringValue(line))
+	rec.SetSeverity(severity)
+	rec.SetSeverityText(text)
+	w.logger.Emit(w.ctx, rec)
+
+	return n, err
+}
+
+// inferSeverity guesses a log severity from the text of a log line, since the
+// stdlib log package has no notion of levels. Anything that doesn't look like
+// a warning or worse is info.
+func inferSeverity(line string) (otellog.Severity, string) {
+	upper := strings.ToUpper(line)
+	switch {
+	case strings.Contains(upper, "PANIC"), strings.Contains(upper, "FATAL"):
+		return otellog.SeverityFatal, "FATAL"
+	case strings.Contains(upper, "ERROR"), strings.Contains(upper, "FAILED"):
+		return otellog.SeverityError, "ERROR"
+	case strings.Contains(upper, "WARN"):
+		return otellog.SeverityWarn, "WARN"
+	case strings.Contains(upper, "DEBUG"):
+		return otellog.SeverityDebug, "DEBUG"
+	default:
+		return otellog.SeverityInfo, "INFO"
+	}
+}
@@ -0,0 +1,94 @@
+package otelinit
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"testing"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// memLogExporter keeps exported records in memory for inspection.
+type memLogExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *memLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *memLogExporter) Shutdown(context.Context) error   { return nil }
+func (e *memLogExporter) ForceFlush(context.Context) error { return nil }
+
+func TestStdlibLogWriter(t *testing.T) {
+	exp := &memLogExporter{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exp)))
+
+	var orig bytes.Buffer
+	w := &stdlibLogWriter{ctx: context.Background(), next: &orig, logger: lp.Logger("test")}
+	installedStdlibWriter.Store(w)
+	defer installedStdlibWriter.Store(nil)
+
+	tid, _ := trace.TraceIDFromHex("f61fc53f926e07a9c3893b1a722e1b65")
+	sid, _ := trace.SpanIDFromHex("7a2d6a804f3de137")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	logger := LogWithContext(ctx)
+	logger.SetFlags(0)
+	logger.SetPrefix("")
+	logger.Println("connection failed: boom")
+
+	if orig.String() != "connection failed: boom\n" {
+		t.Errorf("original destination did not get the log line, got %q", orig.String())
+	}
+
+	if len(exp.records) != 1 {
+		t.Fatalf("expected exactly 1 log record, got %d", len(exp.records))
+	}
+	rec := exp.records[0]
+	if rec.Body().AsString() != "connection failed: boom" {
+		t.Errorf("unexpected log body %q", rec.Body().AsString())
+	}
+	if rec.Severity() != otellog.SeverityError {
+		t.Errorf("expected severity %s, got %s", otellog.SeverityError, rec.Severity())
+	}
+	if rec.TraceID() != tid || rec.SpanID() != sid {
+		t.Errorf("log record is not correlated with the span in context, got trace %s span %s", rec.TraceID(), rec.SpanID())
+	}
+}
+
+func TestLogWithContextInert(t *testing.T) {
+	if LogWithContext(context.Background()) != log.Default() {
+		t.Error("expected the default logger when no writer is installed")
+	}
+}
+
+func TestInferSeverity(t *testing.T) {
+	tests := map[string]otellog.Severity{
+		"panic: runtime error":              otellog.SeverityFatal,
+		"FATAL could not start":             otellog.SeverityFatal,
+		"error reading config":              otellog.SeverityError,
+		"shutdown of exporter failed: nope": otellog.SeverityError,
+		"Warning: falling back to defaults": otellog.SeverityWarn,
+		"debug: cache size 12":              otellog.SeverityDebug,
+		"listening on :8080":                otellog.SeverityInfo,
+	}
+
+	for line, want := range tests {
+		got, _ := inferSeverity(line)
+		if got != want {
+			t.Errorf("inferSeverity(%q) = %s, want %s", line, got, want)
+		}
+	}
+}
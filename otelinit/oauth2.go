@@ -0,0 +1,113 @@
+package otelinit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc/credentials"
+)
+
+// oauth2EarlyExpiry is how long before a token's expiry it gets refreshed, so
+// an export never goes out with a token that expires in flight.
+const oauth2EarlyExpiry = 30 * time.Second
+
+// oauth2Credentials is a gRPC PerRPCCredentials that attaches OAuth2
+// client-credentials tokens to every export. Tokens are cached and only
+// fetched again shortly before they expire.
+type oauth2Credentials struct {
+	tokens oauth2.TokenSource
+	secure bool
+}
+
+// newOAuth2Credentials returns nil when no token URL is configured.
+func (c Config) newOAuth2Credentials() *oauth2Credentials {
+	if c.OAuth2TokenURL == "" {
+		return nil
+	}
+
+	src := &clientSecretFileSource{
+		cfg: clientcredentials.Config{
+			ClientID: c.OAuth2ClientID,
+			TokenURL: c.OAuth2TokenURL,
+			Scopes:   c.OAuth2Scopes,
+		},
+		secretFile: c.OAuth2ClientSecretFile,
+		client:     c.httpClient(),
+	}
+
+	return &oauth2Credentials{
+		tokens: oauth2.ReuseTokenSourceWithExpiry(nil, src, oauth2EarlyExpiry),
+		secure: !c.Insecure,
+	}
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials. The token
+// source has no context of its own, so a slow token fetch carries on in the
+// background, bounded by the HTTP client's timeout, while the RPC gives up
+// at its deadline.
+func (oc *oauth2Credentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	type result struct {
+		token *oauth2.Token
+		err   error
+	}
+	fetched := make(chan result, 1)
+	go func() {
+		token, err := oc.tokens.Token()
+		fetched <- result{token, err}
+	}()
+
+	var token *oauth2.Token
+	select {
+	case r := <-fetched:
+		if r.err != nil {
+			return nil, fmt.Errorf("could not get OAuth2 token for OTLP export: %w", r.err)
+		}
+		token = r.token
+	case <-ctx.Done():
+		return nil, fmt.Errorf("could not get OAuth2 token for OTLP export: %w", ctx.Err())
+	}
+
+	return map[string]string{
+		"authorization": token.Type() + " " + token.AccessToken,
+	}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. Tokens
+// are only sent in the clear when plaintext was already allowed for the
+// endpoint.
+func (oc *oauth2Credentials) RequireTransportSecurity() bool {
+	return oc.secure
+}
+
+// clientSecretFileSource is an oauth2.TokenSource for the client-credentials
+// flow that reads the client secret from a file every time it fetches a
+// token, so a rotated secret is picked up without a restart.
+type clientSecretFileSource struct {
+	cfg        clientcredentials.Config
+	secretFile string
+	client     *http.Client
+}
+
+// Token implements oauth2.TokenSource.
+func (s *clientSecretFileSource) Token() (*oauth2.Token, error) {
+	cfg := s.cfg
+	if s.secretFile != "" {
+		secret, err := os.ReadFile(s.secretFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read OAuth2 client secret file: %w", err)
+		}
+		cfg.ClientSecret = strings.TrimSpace(string(secret))
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, s.client)
+	return cfg.Token(ctx)
+}
+
+// make sure oauth2Credentials stays a PerRPCCredentials
+var _ credentials.PerRPCCredentials = (*oauth2Credentials)(nil)
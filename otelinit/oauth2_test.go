@@ -0,0 +1,94 @@
+package otelinit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOAuth2Credentials(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "client-secret")
+	writeFile(t, secretFile, "hunter2\n")
+
+	// a fake token server that checks the client credentials and hands out
+	// numbered tokens so the test can tell if one was reused
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "my-service" || secret != "hunter2" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("scope") != "traces:write metrics:write" {
+			http.Error(w, `{"error":"invalid_scope"}`, http.StatusBadRequest)
+			return
+		}
+
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "token-" + strconv.Itoa(int(n)),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	oc := Config{
+		OAuth2TokenURL:         tokenServer.URL,
+		OAuth2ClientID:         "my-service",
+		OAuth2ClientSecretFile: secretFile,
+		OAuth2Scopes:           []string{"traces:write", "metrics:write"},
+	}.newOAuth2Credentials()
+
+	for i := 0; i < 3; i++ {
+		md, err := oc.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("GetRequestMetadata failed: %s", err)
+		}
+		if md["authorization"] != "Bearer token-1" {
+			t.Errorf("expected the first token to be used, got %q", md["authorization"])
+		}
+	}
+
+	if issued.Load() != 1 {
+		t.Errorf("expected the token to be cached, but %d were issued", issued.Load())
+	}
+}
+
+func TestOAuth2CredentialsHangingServer(t *testing.T) {
+	release := make(chan struct{})
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer tokenServer.Close()
+	defer close(release)
+
+	oc := Config{OAuth2TokenURL: tokenServer.URL, OAuth2ClientID: "my-service"}.newOAuth2Credentials()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := oc.GetRequestMetadata(ctx); err == nil {
+		t.Error("expected GetRequestMetadata to fail when the token server hangs")
+	}
+	if waited := time.Since(start); waited > 2*time.Second {
+		t.Errorf("expected GetRequestMetadata to give up at the RPC deadline, waited %s", waited)
+	}
+
+	if timeout := (Config{}).httpClient().Timeout; timeout != httpClientTimeout {
+		t.Errorf("expected the HTTP client to time out after %s, got %s", httpClientTimeout, timeout)
+	}
+}
+
+func TestOAuth2CredentialsUnconfigured(t *testing.T) {
+	if oc := (Config{}).newOAuth2Credentials(); oc != nil {
+		t.Error("expected no OAuth2 credentials without a token URL")
+	}
+}
@@ -0,0 +1,51 @@
+package otelinit
+
+import (
+	"log/slog"
+
+	"google.golang.org/grpc"
+)
+
+// Option is a functional option for InitOpenTelemetry. Configuration still
+// comes from the environment; options are for behavior that only makes sense
+// to turn on from code.
+type Option func(*options)
+
+// options holds the settings collected from Option funcs.
+type options struct {
+	stdlibLog        bool
+	logger           *slog.Logger
+	shutdownOnSignal bool
+	flushOnSignal    bool
+	dialOptions      []grpc.DialOption
+	lazyBufferSize   int
+	reloadOnSIGHUP   bool
+}
+
+// newOptions applies the provided Option funcs over the defaults.
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDialOptions passes extra gRPC dial options to the OTLP exporters, for
+// tuning that isn't available through the environment. They are applied
+// after otelinit's own options, so they take precedence.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// WithStdlibLog redirects the standard library log package's output through
+// OpenTelemetry logs. Lines are still written to the original destination
+// and are also emitted as OTLP log records. Has no effect when otelinit is
+// inert (no endpoint configured).
+func WithStdlibLog() Option {
+	return func(o *options) {
+		o.stdlibLog = true
+	}
+}
@@ -0,0 +1,184 @@
+package otelinit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyForEndpoint works out which HTTP CONNECT proxy, if any, to use to
+// reach the endpoint. OTEL_INIT_PROXY wins when set, otherwise the usual
+// HTTPS_PROXY and NO_PROXY environment variables decide. Unix sockets and
+// srv+dns endpoints never go through a proxy, the latter because the proxy
+// would have to do the SRV lookup.
+func proxyForEndpoint(endpoint string) string {
+	if endpoint == "" || strings.HasPrefix(endpoint, unixScheme) || strings.HasPrefix(endpoint, srvScheme) {
+		return ""
+	}
+
+	if override := os.Getenv("OTEL_INIT_PROXY"); override != "" {
+		u, err := url.Parse(override)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			logger().Warn("invalid proxy URL, try something like http://proxy:3128, connecting directly",
+				"env", "OTEL_INIT_PROXY")
+			return ""
+		}
+		return override
+	}
+
+	// gRPC over TLS is tunneled the same way HTTPS is, so HTTPS_PROXY is the
+	// one that applies
+	u, err := httpproxy.FromEnvironment().ProxyFunc()(&url.URL{Scheme: "https", Host: endpoint})
+	if err != nil {
+		logger().Warn("invalid proxy in environment, connecting directly", "env", "HTTPS_PROXY", "error", err)
+		return ""
+	}
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+// RedactedProxy returns the proxy URL with any password replaced, so it is
+// safe to print.
+func (c Config) RedactedProxy() string {
+	if c.Proxy == "" {
+		return ""
+	}
+	u, err := url.Parse(c.Proxy)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// proxyURL returns the parsed proxy URL or nil when there is no proxy.
+func (c Config) proxyURL() *url.URL {
+	if c.Proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(c.Proxy)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// grpcTarget returns the target to hand to gRPC. When going through a proxy
+// the name has to reach the proxy unresolved, since hosts that can only get
+// out through a proxy often can't resolve outside names either. Round-robin
+// load balancing needs the dns resolver so that every address behind the
+// name is known, not just the first one.
+func (c Config) grpcTarget() string {
+	switch {
+	case c.isSRVEndpoint():
+		// the triple slash puts the name in the path, where gRPC expects it
+		return srvScheme + "/" + strings.TrimPrefix(c.Endpoint, srvScheme)
+	case c.Proxy != "":
+		return "passthrough:///" + c.Endpoint
+	case c.LoadBalancing == "round_robin" && !c.isUnixSocket():
+		return "dns:///" + c.Endpoint
+	default:
+		return c.Endpoint
+	}
+}
+
+// httpClientTimeout bounds the HTTP calls otelinit makes, so a server that
+// hangs can't hold up exports forever. It matches the OTLP export timeout.
+const httpClientTimeout = 10 * time.Second
+
+// httpClient returns an HTTP client for the HTTP calls otelinit makes, like
+// fetching OAuth2 tokens. It uses the same proxy as the exporters, or the
+// environment's proxy settings when the exporters aren't using one.
+func (c Config) httpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if u := c.proxyURL(); u != nil {
+		transport.Proxy = http.ProxyURL(u)
+	}
+	return &http.Client{Transport: transport, Timeout: httpClientTimeout}
+}
+
+// dialProxy returns a dialer for grpc.WithContextDialer that opens a tunnel
+// to addr through an HTTP CONNECT proxy.
+func dialProxy(proxy *url.URL) func(context.Context, string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		var d net.Dialer
+		proxyAddr := proxy.Host
+		if proxy.Port() == "" {
+			if proxy.Scheme == "https" {
+				proxyAddr = net.JoinHostPort(proxy.Hostname(), "443")
+			} else {
+				proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+			}
+		}
+
+		conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to proxy %s: %w", proxy.Redacted(), err)
+		}
+		if proxy.Scheme == "https" {
+			conn = tls.Client(conn, &tls.Config{ServerName: proxy.Hostname(), MinVersion: tls.VersionTLS12})
+		}
+
+		// make sure a stuck proxy doesn't outlive the dial
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+			defer conn.SetDeadline(time.Time{})
+		}
+
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: http.Header{},
+		}
+		if user := proxy.User; user != nil {
+			pass, _ := user.Password()
+			creds := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + pass))
+			req.Header.Set("Proxy-Authorization", "Basic "+creds)
+		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("could not send CONNECT to proxy %s: %w", proxy.Redacted(), err)
+		}
+
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("could not read CONNECT response from proxy %s: %w", proxy.Redacted(), err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s refused CONNECT to %s: %s", proxy.Redacted(), addr, resp.Status)
+		}
+
+		// the proxy might have sent bytes from the other side already
+		if br.Buffered() > 0 {
+			return &bufferedConn{Conn: conn, r: br}, nil
+		}
+		return conn, nil
+	}
+}
+
+// bufferedConn is a net.Conn that reads through a bufio.Reader first so
+// nothing read ahead while parsing the CONNECT response is lost.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (bc *bufferedConn) Read(p []byte) (int, error) {
+	return bc.r.Read(p)
+}
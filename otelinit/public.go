@@ -1,19 +1,34 @@
 package otelinit
 
-import "context"
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+)
 
 // OtelShutdown is a function that should be called with context
 // when you want to shut down OpenTelemetry, usually as a defer
-// in main.
-type OtelShutdown func(context.Context)
+// in main. It returns the errors from shutting down every signal
+// joined together, or nil if everything made it out.
+type OtelShutdown func(context.Context) error
 
 // InitOpenTelemetry sets up the OpenTelemetry plumbing so it's ready to use.
 // It requires a context.Context and service name string that is the name of
 // your service or application.
 // TODO: should even this be overrideable via envvars?
+// Optional behavior can be turned on by passing Option funcs.
 // Returns context and a func() that encapuslates clean shutdown.
-func InitOpenTelemetry(ctx context.Context, serviceName string) (context.Context, OtelShutdown) {
-	c := newConfig(serviceName)
+func InitOpenTelemetry(ctx context.Context, serviceName string, opts ...Option) (context.Context, OtelShutdown) {
+	o := newOptions(opts)
+	if o.logger != nil {
+		SetLogger(o.logger)
+	}
+
+	c, err := newConfig(serviceName)
+	if err != nil {
+		logger().Error("OpenTelemetry configuration is invalid, staying inert", "error", err)
+	}
 
 	// no idea if this is gonna work...
 	// or even if this is a good idea but it would be well out of most folks'
@@ -21,19 +36,36 @@ func InitOpenTelemetry(ctx context.Context, serviceName string) (context.Context
 	// and it's a teensy amount of memory
 	ctx = context.WithValue(ctx, "otel-init-config", &c)
 
-	if c.Endpoint != "" {
-		ctx, tracingShutdown := c.initTracing(ctx)
+	if c.enabled() || o.lazyBufferSize > 0 {
+		otel.SetErrorHandler(errHandler)
+
+		res := c.newResource(ctx)
+		ctx, tracingShutdown := c.initTracing(ctx, res, o)
 		// TODO: initMetrics()
-		// TODO: initLogs()
 
-		return ctx, func(ctx context.Context) {
-			tracingShutdown(ctx)
+		logsShutdown := func(context.Context) error { return nil }
+		if o.stdlibLog && c.Endpoint != "" {
+			ctx, logsShutdown = c.initLogs(ctx, res, o)
+		}
+
+		shutdown := c.withShutdownDeadline(func(ctx context.Context) error {
+			defer clearFlushers()
+			err := errors.Join(logsShutdown(ctx), tracingShutdown(ctx))
+			errHandler.logSummary()
+			return err
+		})
+		if o.shutdownOnSignal {
+			shutdown = trapSignals(shutdown)
+		} else if o.flushOnSignal {
+			shutdown = c.flushOnSignals(shutdown)
 		}
+
+		return ctx, shutdown
 	}
 
 	// no configuration, nothing to do, the calling code is inert
 	// config is available in the returned context (for test/debug)
-	return ctx, func(context.Context) {}
+	return ctx, func(context.Context) error { return nil }
 }
 
 // ConfigFromContext extracts the Config struct from the provided context.
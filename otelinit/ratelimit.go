@@ -0,0 +1,111 @@
+package otelinit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultSamplerRate is how many new traces per second the rate limiting
+// sampler keeps when OTEL_TRACES_SAMPLER_ARG isn't set.
+const defaultSamplerRate = 100
+
+// RateLimitStats counts the decisions of the rate limiting sampler, across
+// reloads.
+type RateLimitStats struct {
+	Sampled uint64 `json:"sampled"`
+	Dropped uint64 `json:"dropped"`
+}
+
+var rateLimitSampled, rateLimitDropped atomic.Uint64
+
+// RateLimitingStats returns the counts of traces kept and dropped by the
+// parentbased_ratelimiting sampler.
+func RateLimitingStats() RateLimitStats {
+	return RateLimitStats{
+		Sampled: rateLimitSampled.Load(),
+		Dropped: rateLimitDropped.Load(),
+	}
+}
+
+// samplerRate parses SamplerArg as a number of traces per second. Anything
+// that isn't a positive number is logged and gives the default.
+func (c Config) samplerRate() float64 {
+	if c.SamplerArg == "" {
+		return defaultSamplerRate
+	}
+	rate, err := strconv.ParseFloat(c.SamplerArg, 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		logger().Warn("invalid sampler rate, try a number of traces per second like 100",
+			"env", "OTEL_TRACES_SAMPLER_ARG", "value", c.SamplerArg)
+		return defaultSamplerRate
+	}
+	return rate
+}
+
+// rateLimitingSampler keeps at most rate new traces per second, using a
+// token bucket that holds a second's worth of tokens so short bursts get
+// through. It is meant to be the root sampler of a ParentBased sampler,
+// which takes care of honoring sampled parents.
+type rateLimitingSampler struct {
+	rate float64
+	now  func() time.Time // for tests
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimitingSampler(rate float64) *rateLimitingSampler {
+	return &rateLimitingSampler{
+		rate:   rate,
+		now:    time.Now,
+		tokens: max(rate, 1),
+	}
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (rs *rateLimitingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := sdktrace.SamplingResult{
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+
+	if rs.take() {
+		rateLimitSampled.Add(1)
+		result.Decision = sdktrace.RecordAndSample
+	} else {
+		rateLimitDropped.Add(1)
+		result.Decision = sdktrace.Drop
+	}
+	return result
+}
+
+// take refills the bucket for the time since the last call and takes a
+// token from it if there is one.
+func (rs *rateLimitingSampler) take() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := rs.now()
+	if !rs.last.IsZero() {
+		rs.tokens = min(max(rs.rate, 1), rs.tokens+now.Sub(rs.last).Seconds()*rs.rate)
+	}
+	rs.last = now
+
+	if rs.tokens < 1 {
+		return false
+	}
+	rs.tokens--
+	return true
+}
+
+// Description implements sdktrace.Sampler.
+func (rs *rateLimitingSampler) Description() string {
+	return fmt.Sprintf("RateLimitingSampler{%g}", rs.rate)
+}
@@ -0,0 +1,108 @@
+package otelinit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRateLimitingSampler(t *testing.T) {
+	rs := newRateLimitingSampler(2)
+	now := time.Unix(1700000000, 0)
+	rs.now = func() time.Time { return now }
+
+	decide := func() sdktrace.SamplingDecision {
+		return rs.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background()}).Decision
+	}
+
+	before := RateLimitingStats()
+
+	// a full bucket lets a second's worth through, then nothing
+	for i := 0; i < 2; i++ {
+		if got := decide(); got != sdktrace.RecordAndSample {
+			t.Fatalf("expected trace %d to be sampled, got %v", i, got)
+		}
+	}
+	if got := decide(); got != sdktrace.Drop {
+		t.Fatalf("expected the third trace to be dropped, got %v", got)
+	}
+
+	// half a second refills one token
+	now = now.Add(500 * time.Millisecond)
+	if got := decide(); got != sdktrace.RecordAndSample {
+		t.Errorf("expected a trace after the refill, got %v", got)
+	}
+	if got := decide(); got != sdktrace.Drop {
+		t.Errorf("expected the bucket to be empty again, got %v", got)
+	}
+
+	// a long pause doesn't bank more than a second's worth
+	now = now.Add(time.Minute)
+	sampled := 0
+	for i := 0; i < 10; i++ {
+		if decide() == sdktrace.RecordAndSample {
+			sampled++
+		}
+	}
+	if sampled != 2 {
+		t.Errorf("expected the bucket to hold 2 tokens at most, got %d", sampled)
+	}
+
+	after := RateLimitingStats()
+	if got := after.Sampled - before.Sampled; got != 5 {
+		t.Errorf("expected 5 sampled decisions counted, got %d", got)
+	}
+	if got := after.Dropped - before.Dropped; got != 10 {
+		t.Errorf("expected 10 dropped decisions counted, got %d", got)
+	}
+}
+
+func TestRateLimitingHonorsSampledParents(t *testing.T) {
+	c := Config{Sampler: "parentbased_ratelimiting", SamplerArg: "1"}
+	s := c.newSampler()
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+
+	// far more than the limit, every one is kept because the parent was
+	for i := 0; i < 10; i++ {
+		got := s.ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx, TraceID: parent.TraceID()}).Decision
+		if got != sdktrace.RecordAndSample {
+			t.Fatalf("expected a sampled remote parent to be honored, got %v", got)
+		}
+	}
+}
+
+func TestRateLimitingSetRatio(t *testing.T) {
+	ss := newSwappableSampler(Config{Sampler: "parentbased_ratelimiting", SamplerArg: "10"})
+	before := ss.Description()
+	if err := ss.setRatio(1); err == nil {
+		t.Error("expected setting a ratio on the rate limiting sampler to fail")
+	}
+	if got := ss.Description(); got != before {
+		t.Errorf("expected the rate limiting sampler to be kept, got %q", got)
+	}
+}
+
+func TestSamplerRate(t *testing.T) {
+	tests := map[string]float64{
+		"":     defaultSamplerRate,
+		"250":  250,
+		"0.5":  0.5,
+		"0":    defaultSamplerRate,
+		"fast": defaultSamplerRate,
+	}
+	for arg, want := range tests {
+		if got := (Config{SamplerArg: arg}).samplerRate(); got != want {
+			t.Errorf("expected rate %v for %q, got %v", want, arg, got)
+		}
+	}
+}
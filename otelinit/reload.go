@@ -0,0 +1,188 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// currentTracing is the tracing set up by InitOpenTelemetry, for Reload.
+var currentTracing atomic.Pointer[liveTracing]
+
+// liveTracing holds the parts of the running tracer provider that Reload
+// can replace.
+type liveTracing struct {
+	serviceName string
+	o           options
+	processor   *swappableProcessor
+	sampler     *swappableSampler
+}
+
+// WithReloadOnSIGHUP calls Reload whenever the process gets SIGHUP, until
+// shutdown. Failed reloads are logged and the running configuration is
+// kept. Has no effect when otelinit is inert.
+func WithReloadOnSIGHUP() Option {
+	return func(o *options) {
+		o.reloadOnSIGHUP = true
+	}
+}
+
+// Reload reads the environment again and applies it to the running tracer
+// provider: new exporters, with new endpoints, headers and credentials,
+// replace the old ones and the sampler is replaced too. Spans already
+// handed to the old exporters are flushed to them first. When the new
+// configuration is invalid or has nowhere to send spans, an error is
+// returned and the running configuration is kept. Logs are not reloaded.
+// While spans are being buffered for AttachExporter, Reload refuses to run
+// since it would throw the buffer away.
+func Reload(ctx context.Context) error {
+	lt := currentTracing.Load()
+	if lt == nil {
+		return errors.New("OpenTelemetry is not running, nothing to reload")
+	}
+	if le := currentLazy.Load(); le != nil && !le.attached() {
+		return errors.New("spans are being buffered until an exporter is attached, call AttachExporter before reloading")
+	}
+
+	c, err := newConfig(lt.serviceName)
+	if err != nil {
+		return fmt.Errorf("new configuration is invalid, keeping the running one: %w", err)
+	}
+	if !c.enabled() {
+		return errors.New("new configuration has nowhere to send spans, keeping the running one")
+	}
+
+	procs, err := c.newSpanProcessors(ctx, lt.o)
+	if err != nil {
+		return fmt.Errorf("could not create exporters, keeping the running ones: %w", err)
+	}
+	if err := lt.processor.swap(ctx, procs); err != nil {
+		return err
+	}
+	lt.sampler.setConfig(c)
+
+	logger().Info("reloaded OpenTelemetry configuration", "endpoint", c.Endpoint, "sampler", c.Sampler)
+	return nil
+}
+
+// reloadOnSignal calls Reload on every SIGHUP until stop is closed.
+func reloadOnSignal(stop <-chan struct{}) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-sigs:
+				if err := Reload(context.Background()); err != nil {
+					logger().Error("reload of OpenTelemetry on SIGHUP failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// namedProcessor is a span processor and the name of the exporter behind
+// it, for error messages.
+type namedProcessor struct {
+	name string
+	sdktrace.SpanProcessor
+}
+
+// swappableProcessor is the span processor otelinit registers with the
+// tracer provider. It fans spans out to a set of processors, one per
+// exporter, that Reload can replace all at once.
+type swappableProcessor struct {
+	current atomic.Pointer[[]namedProcessor]
+
+	mu      sync.Mutex // serializes swap and Shutdown
+	stopped bool
+}
+
+func newSwappableProcessor(procs []namedProcessor) *swappableProcessor {
+	sp := &swappableProcessor{}
+	sp.current.Store(&procs)
+	return sp
+}
+
+// swap flushes the current processors, puts procs in their place, and
+// shuts the old ones down.
+func (sp *swappableProcessor) swap(ctx context.Context, procs []namedProcessor) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if sp.stopped {
+		return errors.New("OpenTelemetry has been shut down")
+	}
+
+	old := *sp.current.Load()
+	var errs []error
+	for _, p := range old {
+		if err := p.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush of OpenTelemetry %s trace exporter failed: %w", p.name, err))
+		}
+	}
+
+	sp.current.Store(&procs)
+
+	// anything that ended between the flush and the swap is still in the
+	// old processors, shutting them down sends it to the old exporters
+	errs = append(errs, shutdownProcessors(ctx, old))
+	return errors.Join(errs...)
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (sp *swappableProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, p := range *sp.current.Load() {
+		p.OnStart(parent, s)
+	}
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (sp *swappableProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	for _, p := range *sp.current.Load() {
+		p.OnEnd(s)
+	}
+}
+
+// ForceFlush implements sdktrace.SpanProcessor.
+func (sp *swappableProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, p := range *sp.current.Load() {
+		errs = append(errs, p.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown implements sdktrace.SpanProcessor. The processors are shut down
+// one at a time, each flushes its own exporter and then shuts it down.
+func (sp *swappableProcessor) Shutdown(ctx context.Context) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if sp.stopped {
+		return nil
+	}
+	sp.stopped = true
+	return shutdownProcessors(ctx, *sp.current.Load())
+}
+
+func shutdownProcessors(ctx context.Context, procs []namedProcessor) error {
+	var errs []error
+	for _, p := range procs {
+		if err := p.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown of OpenTelemetry %s trace exporter failed: %w", p.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
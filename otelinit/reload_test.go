@@ -0,0 +1,130 @@
+package otelinit
+
+import (
+	"context"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+)
+
+func TestReload(t *testing.T) {
+	first := startFakeCollector(t, "tcp", "127.0.0.1:0")
+	second := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+first.addr)
+
+	ctx, shutdown := InitOpenTelemetry(context.Background(), testServiceName)
+	defer shutdown(ctx)
+
+	_, span := otel.Tracer("test").Start(ctx, "before")
+	span.End()
+
+	// a bad config is refused and the running one is kept
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "asdf asdf asdf")
+	if err := Reload(ctx); err == nil {
+		t.Error("expected reloading an invalid config to fail")
+	}
+
+	// the span still in the batch goes to the first collector on reload
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+second.addr)
+	if err := Reload(ctx); err != nil {
+		t.Fatalf("reload failed: %s", err)
+	}
+	if diff := cmp.Diff([]string{"before"}, first.spanNames()); diff != "" {
+		t.Errorf("first collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+
+	_, span = otel.Tracer("test").Start(ctx, "after")
+	span.End()
+	if err := Flush(ctx); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+	if diff := cmp.Diff([]string{"after"}, second.spanNames()); diff != "" {
+		t.Errorf("second collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+
+	// the sampler is replaced too
+	os.Setenv("OTEL_TRACES_SAMPLER", "always_off")
+	if err := Reload(ctx); err != nil {
+		t.Fatalf("reload failed: %s", err)
+	}
+	_, span = otel.Tracer("test").Start(ctx, "unsampled")
+	if span.IsRecording() {
+		t.Error("expected the reloaded sampler to drop the span")
+	}
+	span.End()
+}
+
+func TestReloadWhileBuffering(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	ctx, shutdown := InitOpenTelemetry(context.Background(), testServiceName, WithBufferUntilAttached(10))
+	defer shutdown(ctx)
+
+	_, span := otel.Tracer("test").Start(ctx, "buffered")
+	span.End()
+	if err := Flush(ctx); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+
+	// reloading would throw the buffer away, so it is refused
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+fc.addr)
+	if err := Reload(ctx); err == nil {
+		t.Fatal("expected reloading while buffering to fail")
+	}
+
+	if err := AttachExporter(ctx, Config{Servicename: testServiceName, Endpoint: fc.addr, Insecure: true}); err != nil {
+		t.Fatalf("attach failed: %s", err)
+	}
+	if diff := cmp.Diff([]string{"buffered"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the buffered spans (-want +got):\n%s", diff)
+	}
+
+	// once attached, reloading is fine
+	if err := Reload(ctx); err != nil {
+		t.Errorf("reload after attaching failed: %s", err)
+	}
+}
+
+func TestReloadNotRunning(t *testing.T) {
+	if err := Reload(context.Background()); err == nil {
+		t.Error("expected reload without InitOpenTelemetry to fail")
+	}
+}
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	first := startFakeCollector(t, "tcp", "127.0.0.1:0")
+	second := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+first.addr)
+
+	ctx, shutdown := InitOpenTelemetry(context.Background(), testServiceName, WithReloadOnSIGHUP())
+	defer shutdown(ctx)
+
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+second.addr)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("could not send SIGHUP: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(second.spanNames(), "after SIGHUP") {
+		if time.Now().After(deadline) {
+			t.Fatal("spans never reached the collector configured before SIGHUP")
+		}
+		_, span := otel.Tracer("test").Start(ctx, "after SIGHUP")
+		span.End()
+		Flush(ctx)
+		time.Sleep(20 * time.Millisecond)
+	}
+}
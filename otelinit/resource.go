@@ -0,0 +1,21 @@
+package otelinit
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// newResource builds the resource shared by all of the signals so that traces
+// and logs show up under the same service name.
+func (c Config) newResource(ctx context.Context) *resource.Resource {
+	// set the service name that will show up in tracing UIs
+	resAttrs := resource.WithAttributes(semconv.ServiceNameKey.String(c.Servicename))
+	res, err := resource.New(ctx, resAttrs)
+	if err != nil {
+		fatal("failed to create OpenTelemetry service name resource", "service_name", c.Servicename, "error", err)
+	}
+
+	return res
+}
@@ -0,0 +1,42 @@
+package otelinit
+
+import "time"
+
+// These match the OTLP exporters' own defaults and are used for any retry
+// setting that isn't configured.
+const (
+	defaultRetryInitialInterval = 5 * time.Second
+	defaultRetryMaxInterval     = 30 * time.Second
+	defaultRetryMaxElapsedTime  = time.Minute
+)
+
+// retryConfig has the same shape as the OTLP exporters' RetryConfig types so
+// it can be converted to either of them.
+type retryConfig struct {
+	Enabled         bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+// retryConfig fills in the exporter defaults for anything left unset.
+func (c Config) retryConfig() retryConfig {
+	rc := retryConfig{
+		Enabled:         !c.RetryDisabled,
+		InitialInterval: defaultRetryInitialInterval,
+		MaxInterval:     defaultRetryMaxInterval,
+		MaxElapsedTime:  defaultRetryMaxElapsedTime,
+	}
+
+	if c.RetryInitialInterval > 0 {
+		rc.InitialInterval = c.RetryInitialInterval
+	}
+	if c.RetryMaxInterval > 0 {
+		rc.MaxInterval = c.RetryMaxInterval
+	}
+	if c.RetryMaxElapsedTime > 0 {
+		rc.MaxElapsedTime = c.RetryMaxElapsedTime
+	}
+
+	return rc
+}
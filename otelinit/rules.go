@@ -0,0 +1,174 @@
+package otelinit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// samplingRulesFile is the format of OTEL_INIT_SAMPLER_RULES_FILE. Rules are
+// checked in order and the first match decides. DefaultRatio, when set,
+// applies to spans no rule matches, otherwise OTEL_TRACES_SAMPLER does.
+type samplingRulesFile struct {
+	DefaultRatio *float64       `json:"default_ratio" yaml:"default_ratio"`
+	Rules        []samplingRule `json:"rules" yaml:"rules"`
+}
+
+// samplingRule matches spans by name, kind and attributes. Empty fields
+// match anything, and all of the attributes must be equal. Ratio is
+// required, so that a missing or misspelled one isn't taken to mean 0.
+type samplingRule struct {
+	Name       string         `json:"name" yaml:"name"`
+	Kind       string         `json:"kind" yaml:"kind"`
+	Attributes map[string]any `json:"attributes" yaml:"attributes"`
+	Ratio      *float64       `json:"ratio" yaml:"ratio"`
+}
+
+// compiledRule is a samplingRule ready to match against.
+type compiledRule struct {
+	name       *regexp.Regexp
+	kind       trace.SpanKind
+	attributes map[string]string
+	sampler    sdktrace.Sampler
+}
+
+// rulesSampler samples with the first rule that matches a span, or the
+// fallback when none do. Only the attributes given when the span is started
+// are available to match on.
+type rulesSampler struct {
+	rules    []compiledRule
+	fallback sdktrace.Sampler
+}
+
+// loadSamplingRules reads a rules file, JSON or YAML by its extension, and
+// returns a sampler that falls back to fallback unless the file has a
+// default ratio.
+func loadSamplingRules(path string, fallback sdktrace.Sampler) (*rulesSampler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file samplingRulesFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		return nil, errors.New("rules file must end in .json, .yaml or .yml")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse rules file: %w", err)
+	}
+
+	rs := &rulesSampler{fallback: fallback}
+	if file.DefaultRatio != nil {
+		if !validRatio(*file.DefaultRatio) {
+			return nil, fmt.Errorf("default_ratio %v is not between 0 and 1", *file.DefaultRatio)
+		}
+		rs.fallback = sdktrace.TraceIDRatioBased(*file.DefaultRatio)
+	}
+
+	for i, rule := range file.Rules {
+		cr, err := rule.compile()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		rs.rules = append(rs.rules, cr)
+	}
+
+	return rs, nil
+}
+
+func (r samplingRule) compile() (compiledRule, error) {
+	var cr compiledRule
+
+	if r.Name != "" {
+		re, err := regexp.Compile(r.Name)
+		if err != nil {
+			return cr, fmt.Errorf("invalid name pattern: %w", err)
+		}
+		cr.name = re
+	}
+
+	if r.Kind != "" {
+		kind, ok := spanKinds[strings.ToLower(r.Kind)]
+		if !ok {
+			return cr, fmt.Errorf("unknown span kind %q, try server, client, producer, consumer or internal", r.Kind)
+		}
+		cr.kind = kind
+	}
+
+	if len(r.Attributes) > 0 {
+		cr.attributes = make(map[string]string, len(r.Attributes))
+		for k, v := range r.Attributes {
+			cr.attributes[k] = fmt.Sprint(v)
+		}
+	}
+
+	if r.Ratio == nil {
+		return cr, errors.New("rule has no ratio")
+	}
+	if !validRatio(*r.Ratio) {
+		return cr, fmt.Errorf("ratio %v is not between 0 and 1", *r.Ratio)
+	}
+	cr.sampler = sdktrace.TraceIDRatioBased(*r.Ratio)
+
+	return cr, nil
+}
+
+// spanKinds maps the kinds allowed in rules files to trace.SpanKind.
+var spanKinds = map[string]trace.SpanKind{
+	"internal": trace.SpanKindInternal,
+	"server":   trace.SpanKindServer,
+	"client":   trace.SpanKindClient,
+	"producer": trace.SpanKindProducer,
+	"consumer": trace.SpanKindConsumer,
+}
+
+// matches reports whether the rule applies to the span being started.
+func (cr compiledRule) matches(p sdktrace.SamplingParameters) bool {
+	if cr.name != nil && !cr.name.MatchString(p.Name) {
+		return false
+	}
+	if cr.kind != trace.SpanKindUnspecified && cr.kind != trace.ValidateSpanKind(p.Kind) {
+		return false
+	}
+
+	found := 0
+	for _, kv := range p.Attributes {
+		want, ok := cr.attributes[string(kv.Key)]
+		if !ok {
+			continue
+		}
+		if kv.Value.Emit() != want {
+			return false
+		}
+		found++
+	}
+	return found == len(cr.attributes)
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (rs *rulesSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, rule := range rs.rules {
+		if rule.matches(p) {
+			return rule.sampler.ShouldSample(p)
+		}
+	}
+	return rs.fallback.ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler.
+func (rs *rulesSampler) Description() string {
+	return fmt.Sprintf("RulesSampler{rules:%d,fallback:%s}", len(rs.rules), rs.fallback.Description())
+}
@@ -0,0 +1,164 @@
+package otelinit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testRulesYAML = `
+default_ratio: 0
+rules:
+  - name: "^GET /healthz$"
+    ratio: 0
+  - attributes:
+      http.route: /metrics
+    ratio: 0
+  - name: "^payment\\."
+    kind: server
+    ratio: 1
+  - attributes:
+      http.route: /checkout
+      http.status_code: 500
+    ratio: 1
+`
+
+func TestRulesSampler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeFile(t, path, testRulesYAML)
+
+	rs, err := loadSamplingRules(path, sdktrace.AlwaysSample())
+	if err != nil {
+		t.Fatalf("could not load rules: %s", err)
+	}
+
+	tests := map[string]struct {
+		p    sdktrace.SamplingParameters
+		want sdktrace.SamplingDecision
+	}{
+		"health check": {
+			p:    sdktrace.SamplingParameters{Name: "GET /healthz", Kind: trace.SpanKindServer},
+			want: sdktrace.Drop,
+		},
+		"metrics scrape by attribute": {
+			p: sdktrace.SamplingParameters{
+				Name:       "GET",
+				Attributes: []attribute.KeyValue{attribute.String("http.route", "/metrics")},
+			},
+			want: sdktrace.Drop,
+		},
+		"payment server span": {
+			p:    sdktrace.SamplingParameters{Name: "payment.Charge", Kind: trace.SpanKindServer},
+			want: sdktrace.RecordAndSample,
+		},
+		"payment client span falls to the default": {
+			p:    sdktrace.SamplingParameters{Name: "payment.Charge", Kind: trace.SpanKindClient},
+			want: sdktrace.Drop,
+		},
+		"all attributes must match": {
+			p: sdktrace.SamplingParameters{
+				Name: "POST",
+				Attributes: []attribute.KeyValue{
+					attribute.String("http.route", "/checkout"),
+					attribute.Int("http.status_code", 500),
+				},
+			},
+			want: sdktrace.RecordAndSample,
+		},
+		"one attribute is not enough": {
+			p: sdktrace.SamplingParameters{
+				Name:       "POST",
+				Attributes: []attribute.KeyValue{attribute.String("http.route", "/checkout")},
+			},
+			want: sdktrace.Drop,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.p.ParentContext = context.Background()
+			if got := rs.ShouldSample(tc.p).Decision; got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRulesSamplerJSONFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	writeFile(t, path, `{"rules": [{"name": "^GET /healthz$", "ratio": 0}]}`)
+
+	c := Config{Sampler: "always_off", SamplerRulesFile: path}
+	s := c.newSampler()
+
+	// with no default ratio in the file, OTEL_TRACES_SAMPLER decides
+	p := sdktrace.SamplingParameters{ParentContext: context.Background(), Name: "GET /orders"}
+	if got := s.ShouldSample(p).Decision; got != sdktrace.Drop {
+		t.Errorf("expected the always_off fallback to drop, got %v", got)
+	}
+	if want := "RulesSampler{rules:1,fallback:AlwaysOffSampler}"; s.Description() != want {
+		t.Errorf("expected sampler %q, got %q", want, s.Description())
+	}
+}
+
+func TestRulesSamplerInvalid(t *testing.T) {
+	tests := map[string]struct {
+		file string
+		data string
+	}{
+		"bad regexp":     {file: "rules.yaml", data: "rules:\n  - name: \"(\"\n    ratio: 1\n"},
+		"bad kind":       {file: "rules.yaml", data: "rules:\n  - kind: sideways\n    ratio: 1\n"},
+		"bad ratio":      {file: "rules.json", data: `{"rules": [{"ratio": 2}]}`},
+		"missing ratio":  {file: "rules.json", data: `{"rules": [{"name": "^health$"}]}`},
+		"misspelt ratio": {file: "rules.yaml", data: "rules:\n  - name: \"^health$\"\n    rato: 0.5\n"},
+		"bad default":    {file: "rules.json", data: `{"default_ratio": -1}`},
+		"unknown format": {file: "rules.toml", data: ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			writeFile(t, path, tc.data)
+
+			if _, err := loadSamplingRules(path, sdktrace.AlwaysSample()); err == nil {
+				t.Error("expected the rules file to be refused")
+			}
+
+			// newSampler carries on without the rules
+			c := Config{SamplerRulesFile: path}
+			want := sdktrace.ParentBased(sdktrace.AlwaysSample()).Description()
+			if got := c.newSampler().Description(); got != want {
+				t.Errorf("expected sampler %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestRulesSamplerSetRatio(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	writeFile(t, path, `{"default_ratio": 0, "rules": [{"name": "^health$", "ratio": 0}]}`)
+
+	ss := newSwappableSampler(Config{Sampler: "parentbased_traceidratio", SamplerArg: "0.1", SamplerRulesFile: path})
+	if err := ss.setRatio(1); err != nil {
+		t.Fatalf("could not set the ratio: %s", err)
+	}
+
+	health := sdktrace.SamplingParameters{ParentContext: context.Background(), Name: "health"}
+	if got := ss.ShouldSample(health).Decision; got != sdktrace.Drop {
+		t.Errorf("expected the rule to keep dropping health checks, got %v", got)
+	}
+
+	// the new ratio replaces the file's default ratio
+	other := sdktrace.SamplingParameters{ParentContext: context.Background(), Name: "GET /orders"}
+	if got := ss.ShouldSample(other).Decision; got != sdktrace.RecordAndSample {
+		t.Errorf("expected unmatched spans to use the new ratio, got %v", got)
+	}
+	want := sdktrace.ParentBased(&rulesSampler{fallback: sdktrace.TraceIDRatioBased(1), rules: make([]compiledRule, 1)}).Description()
+	if got := ss.Description(); got != want {
+		t.Errorf("expected sampler %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,232 @@
+package otelinit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// envSampler reads the sampler name from the named envvar. Unknown names
+// are logged and dropped, which means the default, parentbased_always_on.
+func envSampler(name string) string {
+	val := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+	switch val {
+	case "", "always_on", "always_off", "traceidratio",
+		"parentbased_always_on", "parentbased_always_off", "parentbased_traceidratio",
+		"parentbased_ratelimiting", "consistent_probability", "parentbased_consistent_probability":
+		return val
+	default:
+		logger().Warn("invalid sampler, try parentbased_traceidratio or always_on", "env", name, "value", val)
+		return ""
+	}
+}
+
+// newSampler builds the sampler named in the config, as described for
+// OTEL_TRACES_SAMPLER in the OpenTelemetry spec, plus otelinit's own
+// parentbased_ratelimiting and consistent_probability. When there is a
+// rules file, its rules are checked before the named sampler, but after
+// the parent's decision for parent based samplers, so they only decide for
+// root spans then.
+func (c Config) newSampler() sdktrace.Sampler {
+	return c.buildSampler(false)
+}
+
+// buildSampler does the work for newSampler. With ratioSet, the named
+// sampler's ratio was set at runtime and wins over the rules file's
+// default_ratio.
+func (c Config) buildSampler(ratioSet bool) sdktrace.Sampler {
+	var root sdktrace.Sampler
+	switch strings.TrimPrefix(c.Sampler, "parentbased_") {
+	case "always_off":
+		root = sdktrace.NeverSample()
+	case "traceidratio":
+		root = sdktrace.TraceIDRatioBased(c.samplerRatio())
+	case "ratelimiting":
+		root = newRateLimitingSampler(c.samplerRate())
+	case "consistent_probability":
+		root = newConsistentSampler(c.samplerRatio())
+	default:
+		root = sdktrace.AlwaysSample()
+	}
+
+	if c.SamplerRulesFile != "" {
+		rules, err := loadSamplingRules(c.SamplerRulesFile, root)
+		if err != nil {
+			logger().Error("could not load sampling rules, ignoring them",
+				"env", "OTEL_INIT_SAMPLER_RULES_FILE", "path", c.SamplerRulesFile, "error", err)
+		} else {
+			if ratioSet {
+				rules.fallback = root
+			}
+			root = rules
+		}
+	}
+
+	if c.isParentBasedSampler() {
+		return sdktrace.ParentBased(root)
+	}
+	return root
+}
+
+// isParentBasedSampler reports whether the configured sampler honors the
+// parent's decision.
+func (c Config) isParentBasedSampler() bool {
+	return c.Sampler == "" || strings.HasPrefix(c.Sampler, "parentbased_")
+}
+
+// samplerRatio parses SamplerArg as a ratio between 0 and 1. Anything else
+// is logged and gives 1, as the spec says.
+func (c Config) samplerRatio() float64 {
+	if c.SamplerArg == "" {
+		return 1
+	}
+	ratio, err := strconv.ParseFloat(c.SamplerArg, 64)
+	if err != nil || !validRatio(ratio) {
+		logger().Warn("invalid sampler ratio, try a number between 0 and 1",
+			"env", "OTEL_TRACES_SAMPLER_ARG", "value", c.SamplerArg)
+		return 1
+	}
+	return ratio
+}
+
+// withSampleRatio returns the config with its sampler changed to one that
+// keeps ratio of new traces. Whether parents are honored and whether
+// decisions are consistent stay the same. The rate limiting sampler has no
+// ratio, so it can't be changed this way.
+func (c Config) withSampleRatio(ratio float64) (Config, error) {
+	name := strings.TrimPrefix(c.Sampler, "parentbased_")
+	switch name {
+	case "ratelimiting":
+		return c, errors.New("the parentbased_ratelimiting sampler has no ratio to set, " +
+			"change its rate with OTEL_TRACES_SAMPLER_ARG and Reload")
+	case "consistent_probability":
+	default:
+		name = "traceidratio"
+	}
+	if c.isParentBasedSampler() {
+		name = "parentbased_" + name
+	}
+
+	c.Sampler, c.SamplerArg = name, strconv.FormatFloat(ratio, 'g', -1, 64)
+	return c, nil
+}
+
+// sampleRatio returns the ratio of traces the configured sampler keeps,
+// ignoring what parents decide.
+func (c Config) sampleRatio() float64 {
+	switch c.Sampler {
+	case "always_off", "parentbased_always_off":
+		return 0
+	case "traceidratio", "parentbased_traceidratio",
+		"consistent_probability", "parentbased_consistent_probability":
+		return c.samplerRatio()
+	default:
+		return 1
+	}
+}
+
+// SetSampleRatio replaces the running sampler with one that keeps ratio of
+// new traces, between 0 and 1. Whether the parent's decision is honored,
+// and whether decisions are consistent across services, stays as
+// configured by OTEL_TRACES_SAMPLER. Rules from the rules file still come
+// first, and the ratio applies to the spans they don't match, in place of
+// the file's default_ratio. The ratio holds until the next call or Reload.
+// The parentbased_ratelimiting sampler has no ratio and returns an error.
+func SetSampleRatio(ratio float64) error {
+	lt := currentTracing.Load()
+	if lt == nil {
+		return errors.New("OpenTelemetry is not running, there is no sampler to change")
+	}
+	if !validRatio(ratio) {
+		return fmt.Errorf("sample ratio %v is not between 0 and 1", ratio)
+	}
+
+	if err := lt.sampler.setRatio(ratio); err != nil {
+		return err
+	}
+	logger().Info("changed OpenTelemetry sample ratio", "ratio", ratio)
+	return nil
+}
+
+// SampleRatio returns the ratio of new traces the running sampler keeps, and
+// false when OpenTelemetry is not running.
+func SampleRatio() (float64, bool) {
+	lt := currentTracing.Load()
+	if lt == nil {
+		return 0, false
+	}
+	return lt.sampler.ratio(), true
+}
+
+// validRatio reports whether ratio is a usable sample ratio.
+func validRatio(ratio float64) bool {
+	return ratio >= 0 && ratio <= 1
+}
+
+// swappableSampler is the sampler otelinit installs on the tracer provider,
+// which can't change its sampler once created. It passes every decision to
+// a sampler that can be replaced at any time.
+type swappableSampler struct {
+	current atomic.Pointer[sdktrace.Sampler]
+	// ratioBits is the current sample ratio as math.Float64bits
+	ratioBits atomic.Uint64
+
+	mu sync.Mutex // serializes changes
+	c  Config     // the config the current sampler was built from
+}
+
+func newSwappableSampler(c Config) *swappableSampler {
+	ss := &swappableSampler{}
+	ss.setConfig(c)
+	return ss
+}
+
+// setConfig replaces the sampler with the one in the config.
+func (ss *swappableSampler) setConfig(c Config) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	s := c.newSampler()
+	ss.current.Store(&s)
+	ss.ratioBits.Store(math.Float64bits(c.sampleRatio()))
+	ss.c = c
+}
+
+// setRatio rebuilds the sampler from the current config with only the
+// ratio changed.
+func (ss *swappableSampler) setRatio(ratio float64) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	c, err := ss.c.withSampleRatio(ratio)
+	if err != nil {
+		return err
+	}
+	s := c.buildSampler(true)
+	ss.current.Store(&s)
+	ss.ratioBits.Store(math.Float64bits(ratio))
+	ss.c = c
+	return nil
+}
+
+// ratio returns the current sample ratio.
+func (ss *swappableSampler) ratio() float64 {
+	return math.Float64frombits(ss.ratioBits.Load())
+}
+
+// ShouldSample implements sdktrace.Sampler.
+func (ss *swappableSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*ss.current.Load()).ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler.
+func (ss *swappableSampler) Description() string {
+	return (*ss.current.Load()).Description()
+}
@@ -0,0 +1,90 @@
+package otelinit
+
+import (
+	"math"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNewSampler(t *testing.T) {
+	tests := map[string]struct {
+		c    Config
+		want string
+	}{
+		"default": {
+			c:    Config{},
+			want: sdktrace.ParentBased(sdktrace.AlwaysSample()).Description(),
+		},
+		"always off": {
+			c:    Config{Sampler: "always_off"},
+			want: sdktrace.NeverSample().Description(),
+		},
+		"ratio": {
+			c:    Config{Sampler: "traceidratio", SamplerArg: "0.25"},
+			want: sdktrace.TraceIDRatioBased(0.25).Description(),
+		},
+		"parent based ratio": {
+			c:    Config{Sampler: "parentbased_traceidratio", SamplerArg: "0.5"},
+			want: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.5)).Description(),
+		},
+		"bad ratio means always": {
+			c:    Config{Sampler: "traceidratio", SamplerArg: "2"},
+			want: sdktrace.TraceIDRatioBased(1).Description(),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.c.newSampler().Description(); got != tc.want {
+				t.Errorf("expected sampler %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSwappableSampler(t *testing.T) {
+	ss := newSwappableSampler(Config{Sampler: "always_on"})
+	if got := ss.ShouldSample(sdktrace.SamplingParameters{}).Decision; got != sdktrace.RecordAndSample {
+		t.Errorf("expected RecordAndSample, got %v", got)
+	}
+
+	ss.setConfig(Config{Sampler: "always_off"})
+	if got := ss.ShouldSample(sdktrace.SamplingParameters{}).Decision; got != sdktrace.Drop {
+		t.Errorf("expected Drop after swapping, got %v", got)
+	}
+	if ss.ratio() != 0 {
+		t.Errorf("expected ratio 0 for always_off, got %v", ss.ratio())
+	}
+}
+
+func TestSetSampleRatio(t *testing.T) {
+	if err := SetSampleRatio(0.5); err == nil {
+		t.Error("expected setting the ratio without a running sampler to fail")
+	}
+
+	ss := newSwappableSampler(Config{Sampler: "parentbased_traceidratio", SamplerArg: "0.1"})
+	currentTracing.Store(&liveTracing{sampler: ss})
+	defer currentTracing.Store(nil)
+
+	if ratio, ok := SampleRatio(); !ok || ratio != 0.1 {
+		t.Errorf("expected the configured ratio 0.1, got %v %t", ratio, ok)
+	}
+
+	for _, bad := range []float64{-0.1, 1.5, math.NaN()} {
+		if err := SetSampleRatio(bad); err == nil {
+			t.Errorf("expected ratio %v to be refused", bad)
+		}
+	}
+
+	if err := SetSampleRatio(1); err != nil {
+		t.Fatalf("could not set the ratio: %s", err)
+	}
+	if ratio, _ := SampleRatio(); ratio != 1 {
+		t.Errorf("expected ratio 1, got %v", ratio)
+	}
+	want := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(1)).Description()
+	if got := ss.Description(); got != want {
+		t.Errorf("expected the parent-based setting to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,145 @@
+package otelinit
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is applied when the context given to OtelShutdown
+// has no deadline and OTEL_INIT_SHUTDOWN_TIMEOUT is unset, so that an
+// unreachable collector can't hang process exit.
+const defaultShutdownTimeout = 5 * time.Second
+
+// shutdownTimeout returns the configured shutdown timeout or the default.
+func (c Config) shutdownTimeout() time.Duration {
+	if c.ShutdownTimeout > 0 {
+		return c.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
+}
+
+// withShutdownDeadline wraps an OtelShutdown so that it applies the
+// configured deadline when the caller's context has none, and so that it
+// only runs once no matter how many times it's called. Later calls return
+// the first call's error.
+func (c Config) withShutdownDeadline(shutdown OtelShutdown) OtelShutdown {
+	var once sync.Once
+	var err error
+	return func(ctx context.Context) error {
+		once.Do(func() {
+			if _, ok := ctx.Deadline(); !ok {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.shutdownTimeout())
+				defer cancel()
+			}
+			err = shutdown(ctx)
+		})
+		return err
+	}
+}
+
+// WithShutdownOnSignal traps SIGTERM and SIGINT. When one arrives, otelinit
+// flushes and shuts down with the OTEL_INIT_SHUTDOWN_TIMEOUT deadline, then
+// re-raises the signal so the process exits the way it would have without
+// otelinit. This keeps the last batch of spans from being lost when e.g.
+// Kubernetes terminates a pod. Has no effect when otelinit is inert.
+//
+// Don't combine it with signal handling of your own: re-raising the signal
+// puts back the default handler, which kills the process in the middle of
+// whatever your handler is doing, and spans from then on are lost since
+// tracing is already shut down. Use WithFlushOnSignal instead.
+func WithShutdownOnSignal() Option {
+	return func(o *options) {
+		o.shutdownOnSignal = true
+	}
+}
+
+// WithFlushOnSignal is for programs that handle SIGTERM and SIGINT
+// themselves, e.g. to drain requests. When one arrives, otelinit flushes
+// with the OTEL_INIT_SHUTDOWN_TIMEOUT deadline and leaves everything else
+// to the program, which still has to call the OtelShutdown when it's done.
+// Tracing keeps running, so spans from the drain are exported too. Signals
+// are only watched, not trapped, but without a handler of its own the
+// program won't exit on them. Ignored when WithShutdownOnSignal is given.
+func WithFlushOnSignal() Option {
+	return func(o *options) {
+		o.flushOnSignal = true
+	}
+}
+
+// trapSignals runs shutdown when SIGTERM or SIGINT arrives, then re-raises
+// the signal. The returned OtelShutdown stops the trap before shutting down
+// so a normal exit doesn't leave the handler behind.
+func trapSignals(shutdown OtelShutdown) OtelShutdown {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		select {
+		case sig := <-sigs:
+			logger().Info("shutting down OpenTelemetry on signal", "signal", sig.String())
+			err := shutdown(context.Background())
+			if err != nil {
+				logger().Error("shutdown of OpenTelemetry on signal failed", "signal", sig.String(), "error", err)
+			}
+
+			// put the default behavior back and deliver the signal again
+			signal.Reset(sig)
+			p, err := os.FindProcess(os.Getpid())
+			if err == nil {
+				err = p.Signal(sig)
+			}
+			if err != nil {
+				os.Exit(1)
+			}
+		case <-done:
+		}
+	}()
+
+	return stopWatching(sigs, done, shutdown)
+}
+
+// flushOnSignals flushes every time SIGTERM or SIGINT arrives, and leaves
+// the rest to the program. The returned OtelShutdown stops watching before
+// shutting down.
+func (c Config) flushOnSignals(shutdown OtelShutdown) OtelShutdown {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				logger().Info("flushing OpenTelemetry on signal", "signal", sig.String())
+				ctx, cancel := context.WithTimeout(context.Background(), c.shutdownTimeout())
+				if err := Flush(ctx); err != nil {
+					logger().Error("flush of OpenTelemetry on signal failed", "signal", sig.String(), "error", err)
+				}
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return stopWatching(sigs, done, shutdown)
+}
+
+// stopWatching wraps shutdown so that the first call stops the signal
+// watcher before shutting down.
+func stopWatching(sigs chan os.Signal, done chan struct{}, shutdown OtelShutdown) OtelShutdown {
+	var stop sync.Once
+	return func(ctx context.Context) error {
+		stop.Do(func() {
+			signal.Stop(sigs)
+			close(done)
+		})
+		return shutdown(ctx)
+	}
+}
@@ -0,0 +1,97 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestWithShutdownDeadline(t *testing.T) {
+	c := Config{ShutdownTimeout: time.Minute}
+
+	var calls int
+	var gotDeadline bool
+	boom := errors.New("boom")
+	shutdown := c.withShutdownDeadline(func(ctx context.Context) error {
+		calls++
+		_, gotDeadline = ctx.Deadline()
+		return boom
+	})
+
+	// no deadline on the way in, so one should be added
+	if err := shutdown(context.Background()); err != boom {
+		t.Errorf("expected the shutdown error to be returned, got %v", err)
+	}
+	if !gotDeadline {
+		t.Error("expected a deadline to be applied to a context without one")
+	}
+
+	// second call is a no-op that returns the first result
+	if err := shutdown(context.Background()); err != boom {
+		t.Errorf("expected the first shutdown error on second call, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected shutdown to run exactly once, ran %d times", calls)
+	}
+}
+
+func TestShutdownTimeoutDefault(t *testing.T) {
+	if got := (Config{}).shutdownTimeout(); got != defaultShutdownTimeout {
+		t.Errorf("expected default shutdown timeout %s, got %s", defaultShutdownTimeout, got)
+	}
+}
+
+func TestFlushOnSignal(t *testing.T) {
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+fc.addr)
+
+	// the program's own handler, which otelinit must leave alone
+	appSigs := make(chan os.Signal, 1)
+	signal.Notify(appSigs, syscall.SIGTERM)
+	defer signal.Stop(appSigs)
+
+	ctx, shutdown := InitOpenTelemetry(context.Background(), testServiceName, WithFlushOnSignal())
+	defer shutdown(ctx)
+
+	_, span := otel.Tracer("test").Start(ctx, "before SIGTERM")
+	span.End()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("could not send SIGTERM: %s", err)
+	}
+
+	select {
+	case <-appSigs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the program's handler never got the signal")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Contains(fc.spanNames(), "before SIGTERM") {
+		if time.Now().After(deadline) {
+			t.Fatal("spans were not flushed on SIGTERM")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// tracing is still running while the program drains
+	_, span = otel.Tracer("test").Start(ctx, "draining")
+	if !span.IsRecording() {
+		t.Error("expected tracing to keep running after the signal")
+	}
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+	if !slices.Contains(fc.spanNames(), "draining") {
+		t.Error("expected spans from the drain to be exported at shutdown")
+	}
+}
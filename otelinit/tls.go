@@ -0,0 +1,185 @@
+package otelinit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves TLS material from files that are swapped out from
+// under us, e.g. by cert-manager. Files are checked for changes on every
+// handshake, so rotated certificates are used on the next connection
+// without restarting the process.
+type certReloader struct {
+	caFile   string
+	certFile string
+	keyFile  string
+	// serverName is the endpoint's host, checked against the server's
+	// certificate when the handshake has no name of its own. crypto/tls
+	// leaves the name out of the ConnectionState for IP addresses.
+	serverName string
+
+	mu       sync.Mutex
+	pool     *x509.CertPool
+	caMod    time.Time
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+	loadedCA bool
+}
+
+// tlsConfig returns a tls.Config that loads the CA and client certificate
+// from the configured files and reloads them when they change. Returns nil
+// when none of the files are configured, which means the system roots and
+// no client certificate.
+func (c Config) tlsConfig() *tls.Config {
+	if c.CertificateFile == "" && c.ClientCertificateFile == "" {
+		return nil
+	}
+
+	cr := &certReloader{
+		caFile:   c.CertificateFile,
+		certFile: c.ClientCertificateFile,
+		keyFile:  c.ClientKeyFile,
+	}
+	if host, _, err := net.SplitHostPort(c.Endpoint); err == nil {
+		cr.serverName = host
+	}
+
+	// An initial failure is logged but not fatal: the files may not have
+	// been written yet, and they'll be tried again on each handshake.
+	if err := cr.maybeReload(); err != nil {
+		logger().Warn("could not load OTLP TLS files, will retry on connect", "error", err)
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cr.certFile != "" {
+		cfg.GetClientCertificate = cr.getClientCertificate
+	}
+	if cr.caFile != "" {
+		// RootCAs can't be changed after the fact, so do the verification
+		// ourselves against whatever pool is current.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = cr.verifyConnection
+	}
+
+	return cfg
+}
+
+// getClientCertificate implements tls.Config.GetClientCertificate.
+func (cr *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if err := cr.maybeReload(); err != nil {
+		logger().Warn("could not reload OTLP TLS files, using previous ones", "error", err)
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.cert == nil {
+		return nil, errors.New("no client certificate has been loaded")
+	}
+	return cr.cert, nil
+}
+
+// verifyConnection implements tls.Config.VerifyConnection, checking the
+// server's certificate chain and name against the current CA pool.
+func (cr *certReloader) verifyConnection(cs tls.ConnectionState) error {
+	if err := cr.maybeReload(); err != nil {
+		logger().Warn("could not reload OTLP TLS files, using previous ones", "error", err)
+	}
+
+	cr.mu.Lock()
+	pool := cr.pool
+	cr.mu.Unlock()
+
+	if pool == nil {
+		return errors.New("no CA certificate has been loaded")
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificates")
+	}
+	name := cs.ServerName
+	if name == "" {
+		name = cr.serverName
+	}
+	if name == "" {
+		// an empty name would skip the hostname check entirely
+		return errors.New("no server name to verify the certificate against")
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// maybeReload reloads whichever files changed since they were last loaded.
+func (cr *certReloader) maybeReload() error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	var errs []error
+
+	if cr.caFile != "" {
+		mod, err := modTime(cr.caFile)
+		if err != nil {
+			errs = append(errs, err)
+		} else if !cr.loadedCA || !mod.Equal(cr.caMod) {
+			pem, err := os.ReadFile(cr.caFile)
+			pool := x509.NewCertPool()
+			if err == nil && !pool.AppendCertsFromPEM(pem) {
+				err = fmt.Errorf("no certificates found in %q", cr.caFile)
+			}
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				if cr.loadedCA {
+					logger().Info("reloaded OTLP CA certificate", "path", cr.caFile)
+				}
+				cr.pool, cr.caMod, cr.loadedCA = pool, mod, true
+			}
+		}
+	}
+
+	if cr.certFile != "" {
+		certMod, certErr := modTime(cr.certFile)
+		keyMod, keyErr := modTime(cr.keyFile)
+		if err := errors.Join(certErr, keyErr); err != nil {
+			errs = append(errs, err)
+		} else if cr.cert == nil || !certMod.Equal(cr.certMod) || !keyMod.Equal(cr.keyMod) {
+			cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+			if err != nil {
+				// a half-written rotation will land here, the next
+				// handshake will try again
+				errs = append(errs, err)
+			} else {
+				if cr.cert != nil {
+					logger().Info("reloaded OTLP client certificate", "path", cr.certFile)
+				}
+				cr.cert, cr.certMod, cr.keyMod = &cert, certMod, keyMod
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// modTime returns the modification time of a file.
+func modTime(path string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
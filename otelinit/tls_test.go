@@ -0,0 +1,227 @@
+package otelinit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCA is a throwaway certificate authority for TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "otelinit test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue makes a leaf certificate signed by the CA and returns it and its key
+// in PEM. Server certificates are for localhost and 127.0.0.1 unless other
+// names are given.
+func (ca *testCA) issue(t *testing.T, serial int64, server bool, names ...string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "otelinit test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.DNSNames = []string{"localhost"}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		if len(names) > 0 {
+			tmpl.DNSNames, tmpl.IPAddresses = names, nil
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestCertReloaderRotation(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	certPEM, keyPEM := ca.issue(t, 100, false)
+	writeFile(t, certFile, string(certPEM))
+	writeFile(t, keyFile, string(keyPEM))
+
+	cfg := Config{ClientCertificateFile: certFile, ClientKeyFile: keyFile}.tlsConfig()
+	cert, err := cfg.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("could not get client certificate: %s", err)
+	}
+	if serialOf(t, cert) != 100 {
+		t.Errorf("expected serial 100, got %d", serialOf(t, cert))
+	}
+
+	// rotate, and bump the mtime in case the filesystem is coarse
+	certPEM, keyPEM = ca.issue(t, 200, false)
+	writeFile(t, certFile, string(certPEM))
+	writeFile(t, keyFile, string(keyPEM))
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+
+	cert, err = cfg.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("could not get rotated client certificate: %s", err)
+	}
+	if serialOf(t, cert) != 200 {
+		t.Errorf("expected rotated serial 200, got %d", serialOf(t, cert))
+	}
+
+	// a broken rotation keeps serving the last good certificate
+	writeFile(t, keyFile, "not a key")
+	even := later.Add(time.Minute)
+	os.Chtimes(keyFile, even, even)
+	cert, err = cfg.GetClientCertificate(nil)
+	if err != nil || serialOf(t, cert) != 200 {
+		t.Errorf("expected the previous certificate after a bad rotation, got %v", err)
+	}
+}
+
+func TestMutualTLSExport(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeFile(t, caFile, string(ca.pem))
+	certPEM, keyPEM := ca.issue(t, 2, false)
+	writeFile(t, certFile, string(certPEM))
+	writeFile(t, keyFile, string(keyPEM))
+
+	serverCertPEM, serverKeyPEM := ca.issue(t, 3, true)
+	serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	serverCreds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0", grpc.Creds(serverCreds))
+
+	ctx := context.Background()
+	c := Config{
+		Servicename:           testServiceName,
+		Endpoint:              fc.addr,
+		CertificateFile:       caFile,
+		ClientCertificateFile: certFile,
+		ClientKeyFile:         keyFile,
+	}
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), options{})
+	_, span := otel.Tracer("test").Start(ctx, "over mtls")
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"over mtls"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+}
+
+func TestTLSVerifiesIPEndpoint(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, caFile, string(ca.pem))
+
+	// a certificate from the right CA, but for some other name
+	certPEM, keyPEM := ca.issue(t, 2, true, "elsewhere.internal")
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCreds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{serverCert}})
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0", grpc.Creds(serverCreds))
+
+	c := Config{
+		Servicename:     testServiceName,
+		Endpoint:        fc.addr,
+		CertificateFile: caFile,
+		RetryDisabled:   true,
+	}
+	exporter, err := c.newTraceExporter(context.Background(), options{})
+	if err != nil {
+		t.Fatalf("could not create exporter: %s", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := exporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "wrong name"}}.Snapshots()); err == nil {
+		t.Error("expected export to fail against a certificate for another name")
+	}
+	if names := fc.spanNames(); len(names) != 0 {
+		t.Errorf("expected no spans to arrive, got %q", names)
+	}
+}
+
+// serialOf returns the serial number of a loaded certificate's leaf.
+func serialOf(t *testing.T, cert *tls.Certificate) int64 {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
@@ -0,0 +1,288 @@
+package otelinit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// defaultTraceRoutingRefresh is how often OTEL_INIT_TRACE_ROUTING_DNS is
+// looked up again when OTEL_INIT_TRACE_ROUTING_REFRESH isn't set.
+const defaultTraceRoutingRefresh = 30 * time.Second
+
+// ringReplicas is how many points each endpoint gets on the hash ring. More
+// points spread the traces more evenly.
+const ringReplicas = 100
+
+// hashRing maps trace IDs onto endpoints with consistent hashing, so that
+// adding or removing an endpoint only moves the traces that hashed to it.
+type hashRing struct {
+	hashes []uint64
+	owners []string
+}
+
+func newHashRing(endpoints []string) *hashRing {
+	type point struct {
+		hash  uint64
+		owner string
+	}
+	points := make([]point, 0, len(endpoints)*ringReplicas)
+	for _, ep := range endpoints {
+		for i := 0; i < ringReplicas; i++ {
+			points = append(points, point{hash: hash64([]byte(ep + "#" + strconv.Itoa(i))), owner: ep})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].hash < points[j].hash })
+
+	r := &hashRing{
+		hashes: make([]uint64, len(points)),
+		owners: make([]string, len(points)),
+	}
+	for i, p := range points {
+		r.hashes[i], r.owners[i] = p.hash, p.owner
+	}
+	return r
+}
+
+// lookup returns the endpoint that owns the trace ID, or "" when the ring
+// is empty.
+func (r *hashRing) lookup(id trace.TraceID) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := hash64(id[:])
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+// hash64 is FNV-1a followed by the splitmix64 finalizer, since FNV on its
+// own spreads short, similar keys like "a:4317#1" poorly around the ring.
+func hash64(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// traceIDExporter is a SpanExporter that sends every span of a trace to the
+// same endpoint, which tail sampling in a collector fleet depends on. Each
+// batch is split up by endpoint and the parts are exported in parallel.
+type traceIDExporter struct {
+	newExporter func(endpoint string) (sdktrace.SpanExporter, error)
+
+	mu        sync.RWMutex
+	endpoints []string
+	ring      *hashRing
+	exporters map[string]sdktrace.SpanExporter
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newTraceIDExporter creates a traceIDExporter over c.Endpoints, or over
+// the collectors found at c.TraceRoutingDNS, which are then looked up again
+// periodically. With a single c.Endpoint every trace goes to it.
+func (c Config) newTraceIDExporter(ctx context.Context, o options) (*traceIDExporter, error) {
+	// A records give IP addresses, but the collectors' certificates are
+	// issued for the name that was looked up, so that stays the authority
+	var authority string
+	if c.TraceRoutingDNS != "" && !isSRVName(c.TraceRoutingDNS) {
+		authority = c.TraceRoutingDNS
+	}
+
+	// exporters created later by the refresh shouldn't be tied to the
+	// caller's context
+	ctx = context.WithoutCancel(ctx)
+	te := &traceIDExporter{
+		newExporter: func(endpoint string) (sdktrace.SpanExporter, error) {
+			ec, eo := c.forEndpoint(endpoint), o
+			if authority != "" {
+				ec.Proxy = proxyForEndpoint(authority)
+				eo.dialOptions = append([]grpc.DialOption{grpc.WithAuthority(authority)}, o.dialOptions...)
+			}
+			return ec.newTraceExporter(ctx, eo)
+		},
+		ring:      newHashRing(nil),
+		exporters: map[string]sdktrace.SpanExporter{},
+	}
+
+	if c.TraceRoutingDNS == "" {
+		endpoints := c.Endpoints
+		if len(endpoints) == 0 && c.Endpoint != "" {
+			endpoints = []string{c.Endpoint}
+		}
+		if err := te.update(endpoints); err != nil {
+			return nil, err
+		}
+		return te, nil
+	}
+
+	interval := c.TraceRoutingRefresh
+	if interval == 0 {
+		interval = defaultTraceRoutingRefresh
+	}
+	te.stop, te.done = make(chan struct{}), make(chan struct{})
+	te.refresh(ctx, dnsResolver, c.TraceRoutingDNS)
+	go te.refreshLoop(ctx, dnsResolver, c.TraceRoutingDNS, interval)
+
+	return te, nil
+}
+
+// update replaces the set of endpoints, reusing the exporters for the ones
+// that stay and shutting down the ones that go.
+func (te *traceIDExporter) update(endpoints []string) error {
+	te.mu.RLock()
+	current := te.exporters
+	te.mu.RUnlock()
+
+	exporters := make(map[string]sdktrace.SpanExporter, len(endpoints))
+	for _, ep := range endpoints {
+		if exporter, ok := current[ep]; ok {
+			exporters[ep] = exporter
+			continue
+		}
+		exporter, err := te.newExporter(ep)
+		if err != nil {
+			// the new ones would never be used or shut down otherwise
+			errs := []error{fmt.Errorf("could not create exporter for %q: %w", ep, err)}
+			for created, exporter := range exporters {
+				if _, ok := current[created]; !ok {
+					ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+					errs = append(errs, exporter.Shutdown(ctx))
+					cancel()
+				}
+			}
+			return errors.Join(errs...)
+		}
+		exporters[ep] = exporter
+	}
+
+	// taking the write lock waits for exports in flight, so nothing is
+	// using the removed exporters by the time they are shut down
+	te.mu.Lock()
+	te.endpoints, te.ring, te.exporters = endpoints, newHashRing(endpoints), exporters
+	te.mu.Unlock()
+
+	var errs []error
+	for ep, exporter := range current {
+		if _, ok := exporters[ep]; !ok {
+			ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+			errs = append(errs, exporter.Shutdown(ctx))
+			cancel()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// refresh looks up the collectors again and updates the ring if they
+// changed. Lookup failures keep the previous collectors.
+func (te *traceIDExporter) refresh(ctx context.Context, r resolver, name string) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	endpoints, err := resolveEndpoints(ctx, r, name)
+	if err != nil {
+		logger().Warn("could not look up collectors for trace routing, keeping the previous ones",
+			"env", "OTEL_INIT_TRACE_ROUTING_DNS", "name", name, "error", err)
+		return
+	}
+
+	te.mu.RLock()
+	unchanged := slices.Equal(endpoints, te.endpoints)
+	te.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	logger().Info("trace routing collectors changed", "endpoints", endpoints)
+	if err := te.update(endpoints); err != nil {
+		logger().Warn("could not update trace routing collectors", "error", err)
+	}
+}
+
+func (te *traceIDExporter) refreshLoop(ctx context.Context, r resolver, name string, interval time.Duration) {
+	defer close(te.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-te.stop:
+			return
+		case <-ticker.C:
+			te.refresh(ctx, r, name)
+		}
+	}
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (te *traceIDExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+
+	if len(te.exporters) == 0 {
+		return errors.New("no collectors available for trace routing")
+	}
+
+	batches := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		ep := te.ring.lookup(span.SpanContext().TraceID())
+		batches[ep] = append(batches[ep], span)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 0, len(batches))
+	var errMu sync.Mutex
+	for ep, batch := range batches {
+		wg.Add(1)
+		go func(ep string, batch []sdktrace.ReadOnlySpan) {
+			defer wg.Done()
+			if err := te.exporters[ep].ExportSpans(ctx, batch); err != nil {
+				errMu.Lock()
+				errs = append(errs, fmt.Errorf("export to %q failed: %w", ep, err))
+				errMu.Unlock()
+			}
+		}(ep, batch)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (te *traceIDExporter) Shutdown(ctx context.Context) error {
+	if te.stop != nil {
+		close(te.stop)
+		<-te.done
+	}
+
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	var errs []error
+	for ep, exporter := range te.exporters {
+		if err := exporter.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", ep, err))
+		}
+	}
+	te.exporters = nil
+	return errors.Join(errs...)
+}
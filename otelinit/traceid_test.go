@@ -0,0 +1,308 @@
+package otelinit
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// fakeResolver answers lookups from fixed tables.
+type fakeResolver struct {
+	hosts map[string][]string
+	srvs  map[string][]*net.SRV
+}
+
+func (r fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if srvs, ok := r.srvs[name]; ok {
+		return name, srvs, nil
+	}
+	return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	r := fakeResolver{
+		hosts: map[string][]string{
+			"collectors.internal": {"10.0.0.2", "10.0.0.1"},
+		},
+		srvs: map[string][]*net.SRV{
+			"_otlp._tcp.collectors.internal": {
+				{Target: "collector-b.internal.", Port: 4317},
+				{Target: "collector-a.internal.", Port: 14317},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		name    string
+		want    []string
+		wantErr bool
+	}{
+		"A records": {
+			name: "collectors.internal:4317",
+			want: []string{"10.0.0.1:4317", "10.0.0.2:4317"},
+		},
+		"SRV records": {
+			name: "_otlp._tcp.collectors.internal",
+			want: []string{"collector-a.internal:14317", "collector-b.internal:4317"},
+		},
+		"unknown name": {
+			name:    "nowhere.internal:4317",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveEndpoints(context.Background(), r, tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error state, wanted error: %t, got: %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected endpoints (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHashRingConsistency(t *testing.T) {
+	before := newHashRing([]string{"a:4317", "b:4317", "c:4317"})
+	after := newHashRing([]string{"a:4317", "b:4317", "c:4317", "d:4317"})
+
+	counts := map[string]int{}
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		var id trace.TraceID
+		copy(id[:], fmt.Sprintf("trace-%010d", i))
+
+		owner := before.lookup(id)
+		counts[owner]++
+		if now := after.lookup(id); now != owner {
+			if now != "d:4317" {
+				t.Fatalf("trace %d moved between existing endpoints %s and %s", i, owner, now)
+			}
+			moved++
+		}
+	}
+
+	for ep, n := range counts {
+		if n < 200 {
+			t.Errorf("expected traces to be spread evenly, %s only got %d of 1000", ep, n)
+		}
+	}
+	if moved == 0 || moved > 400 {
+		t.Errorf("expected roughly a quarter of traces to move to the new endpoint, %d did", moved)
+	}
+}
+
+func TestTraceIDExporterRefresh(t *testing.T) {
+	var mu sync.Mutex
+	created := map[string]*stubExporter{}
+	te := &traceIDExporter{
+		newExporter: func(endpoint string) (sdktrace.SpanExporter, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			created[endpoint] = &stubExporter{}
+			return created[endpoint], nil
+		},
+		ring:      newHashRing(nil),
+		exporters: map[string]sdktrace.SpanExporter{},
+	}
+
+	r := fakeResolver{hosts: map[string][]string{"collectors.internal": {"10.0.0.1"}}}
+	te.refresh(context.Background(), r, "collectors.internal:4317")
+	first := created["10.0.0.1:4317"]
+
+	// a second collector shows up, the first keeps its exporter
+	r.hosts["collectors.internal"] = []string{"10.0.0.1", "10.0.0.2"}
+	te.refresh(context.Background(), r, "collectors.internal:4317")
+	if diff := cmp.Diff([]string{"10.0.0.1:4317", "10.0.0.2:4317"}, te.endpoints); diff != "" {
+		t.Errorf("unexpected endpoints after refresh (-want +got):\n%s", diff)
+	}
+	if te.exporters["10.0.0.1:4317"] != first {
+		t.Error("expected the exporter for an unchanged endpoint to be kept")
+	}
+
+	// a failed lookup keeps what was there
+	delete(r.hosts, "collectors.internal")
+	te.refresh(context.Background(), r, "collectors.internal:4317")
+	if len(te.endpoints) != 2 {
+		t.Errorf("expected a failed lookup to keep the endpoints, got %v", te.endpoints)
+	}
+}
+
+func TestTraceIDExporterUpdateFails(t *testing.T) {
+	kept := &stubExporter{}
+	created := map[string]*stubExporter{}
+	te := &traceIDExporter{
+		newExporter: func(endpoint string) (sdktrace.SpanExporter, error) {
+			if endpoint == "10.0.0.3:4317" {
+				return nil, errors.New("no exporter for you")
+			}
+			created[endpoint] = &stubExporter{}
+			return created[endpoint], nil
+		},
+		endpoints: []string{"10.0.0.1:4317"},
+		ring:      newHashRing([]string{"10.0.0.1:4317"}),
+		exporters: map[string]sdktrace.SpanExporter{"10.0.0.1:4317": kept},
+	}
+
+	if err := te.update([]string{"10.0.0.1:4317", "10.0.0.2:4317", "10.0.0.3:4317"}); err == nil {
+		t.Fatal("expected the update to fail")
+	}
+	if !created["10.0.0.2:4317"].shutdown {
+		t.Error("expected the exporter created before the failure to be shut down")
+	}
+	if kept.shutdown || te.exporters["10.0.0.1:4317"] != kept {
+		t.Error("expected the running exporter to be kept")
+	}
+}
+
+func TestTraceIDExporterNoCollectors(t *testing.T) {
+	te := &traceIDExporter{ring: newHashRing(nil)}
+	if err := te.ExportSpans(context.Background(), nil); err == nil {
+		t.Error("expected an error with no collectors")
+	}
+}
+
+func TestTraceIDRouting(t *testing.T) {
+	collectors := []*fakeCollector{
+		startFakeCollector(t, "tcp", "127.0.0.1:0"),
+		startFakeCollector(t, "tcp", "127.0.0.1:0"),
+	}
+
+	ctx := context.Background()
+	c := Config{
+		Servicename:  testServiceName,
+		Endpoint:     collectors[0].addr,
+		Endpoints:    []string{collectors[0].addr, collectors[1].addr},
+		Insecure:     true,
+		TraceRouting: "traceid",
+	}
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), options{})
+
+	// every span of a trace is named after the trace
+	tracer := otel.Tracer("test")
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("trace-%d", i)
+		ctx, root := tracer.Start(ctx, name)
+		_, child := tracer.Start(ctx, name)
+		child.End()
+		root.End()
+	}
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+	clearFlushers()
+
+	seen := map[string]int{}
+	for i, fc := range collectors {
+		names := fc.spanNames()
+		if len(names) == 0 {
+			t.Errorf("collector %d got no spans", i)
+		}
+		for _, name := range names {
+			if owner, ok := seen[name]; ok && owner != i {
+				t.Errorf("spans of %s went to more than one collector", name)
+			}
+			seen[name] = i
+		}
+	}
+	if len(seen) != 20 {
+		t.Errorf("expected all 20 traces to arrive, got %d: %s", len(seen), strings.Join(keys(seen), ","))
+	}
+}
+
+func TestTraceIDRoutingSingleEndpoint(t *testing.T) {
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+
+	c := Config{
+		Servicename:  testServiceName,
+		Endpoint:     fc.addr,
+		Insecure:     true,
+		TraceRouting: "traceid",
+	}
+	te, err := c.newTraceIDExporter(context.Background(), options{})
+	if err != nil {
+		t.Fatalf("could not create exporter: %s", err)
+	}
+	defer te.Shutdown(context.Background())
+
+	if err := te.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "only one"}}.Snapshots()); err != nil {
+		t.Fatalf("export failed: %s", err)
+	}
+	if diff := cmp.Diff([]string{"only one"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+}
+
+func TestTraceIDRoutingDNSTLS(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	writeFile(t, caFile, string(ca.pem))
+
+	// the certificate is only good for the name, not the address it
+	// resolves to
+	certPEM, keyPEM := ca.issue(t, 2, true, "collectors.internal")
+	serverCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCreds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{serverCert}})
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0", grpc.Creds(serverCreds))
+	_, port, _ := net.SplitHostPort(fc.addr)
+
+	prev := dnsResolver
+	dnsResolver = fakeResolver{hosts: map[string][]string{"collectors.internal": {"127.0.0.1"}}}
+	t.Cleanup(func() { dnsResolver = prev })
+
+	c := Config{
+		Servicename:     testServiceName,
+		TraceRouting:    "traceid",
+		TraceRoutingDNS: net.JoinHostPort("collectors.internal", port),
+		CertificateFile: caFile,
+	}
+	te, err := c.newTraceIDExporter(context.Background(), options{})
+	if err != nil {
+		t.Fatalf("could not create exporter: %s", err)
+	}
+	defer te.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := te.ExportSpans(ctx, tracetest.SpanStubs{{Name: "verified"}}.Snapshots()); err != nil {
+		t.Fatalf("export failed: %s", err)
+	}
+	if diff := cmp.Diff([]string{"verified"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+}
+
+func keys(m map[string]int) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -2,44 +2,31 @@ package otelinit
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	otlpgrpc "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
-	"google.golang.org/grpc/credentials"
 )
 
-func (c Config) initTracing(ctx context.Context) (context.Context, OtelShutdown) {
-	// set the service name that will show up in tracing UIs
-	resAttrs := resource.WithAttributes(semconv.ServiceNameKey.String(c.Servicename))
-	res, err := resource.New(ctx, resAttrs)
+func (c Config) initTracing(ctx context.Context, res *resource.Resource, o options) (context.Context, OtelShutdown) {
+	procs, err := c.newSpanProcessors(ctx, o)
 	if err != nil {
-		log.Fatalf("failed to create OpenTelemetry service name resource: %s", err)
+		fatal("failed to configure OTLP exporter", "endpoint", c.Endpoint, "error", err)
 	}
 
-	grpcOpts := []otlpgrpc.Option{otlpgrpc.WithEndpoint(c.Endpoint)}
-	if c.Insecure {
-		grpcOpts = append(grpcOpts, otlpgrpc.WithInsecure())
-	} else {
-		creds := credentials.NewClientTLSFromCert(nil, "")
-		grpcOpts = append(grpcOpts, otlpgrpc.WithTLSCredentials(creds))
-	}
-	// TODO: add TLS client cert auth
-
-	exporter, err := otlpgrpc.New(ctx, grpcOpts...)
-	if err != nil {
-		log.Fatalf("failed to configure OTLP exporter: %s", err)
-	}
-
-	// TODO: more configuration opportunities here
-	bsp := sdktrace.NewBatchSpanProcessor(exporter)
+	// the processors and sampler are wrapped so that Reload can replace
+	// them on the live tracer provider
+	processor := newSwappableProcessor(procs)
+	sampler := newSwappableSampler(c)
 	tracerProvider := sdktrace.NewTracerProvider(
 		sdktrace.WithResource(res),
-		sdktrace.WithSpanProcessor(bsp),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithSpanProcessor(processor),
 	)
 
 	// set global propagator to tracecontext (the default is no-op).
@@ -48,17 +35,84 @@ func (c Config) initTracing(ctx context.Context) (context.Context, OtelShutdown)
 
 	// inject the tracer into the otel globals, start background goroutines
 	otel.SetTracerProvider(tracerProvider)
+	registerFlusher(tracerProvider.ForceFlush)
+
+	lt := &liveTracing{serviceName: c.Servicename, o: o, processor: processor, sampler: sampler}
+	currentTracing.Store(lt)
+
+	stopReload := make(chan struct{})
+	if o.reloadOnSIGHUP {
+		reloadOnSignal(stopReload)
+	}
 
 	// the public function will wrap this in its own shutdown function
-	return ctx, func(ctx context.Context) {
-		err = tracerProvider.Shutdown(ctx)
+	return ctx, func(ctx context.Context) error {
+		close(stopReload)
+		currentTracing.CompareAndSwap(lt, nil)
+
+		// shut the processors down first so each exporter's errors are
+		// reported under its own name
+		var errs []error
+		errs = append(errs, processor.Shutdown(ctx))
+
+		err := tracerProvider.Shutdown(ctx)
 		if err != nil {
-			log.Printf("shutdown of OpenTelemetry tracerProvider failed: %s", err)
+			errs = append(errs, fmt.Errorf("shutdown of OpenTelemetry tracerProvider failed: %w", err))
 		}
 
-		err = exporter.Shutdown(ctx)
+		return errors.Join(errs...)
+	}
+}
+
+// newSpanProcessors creates the exporters in the config, each with a batch
+// span processor of its own so a slow or failing one doesn't hold up the
+// others. Exporters other than otlp that can't be created are logged and
+// left out, a broken otlp exporter is returned as an error.
+func (c Config) newSpanProcessors(ctx context.Context, o options) ([]namedProcessor, error) {
+	names := c.tracesExporters()
+	if o.lazyBufferSize > 0 && !c.enabled() {
+		// nowhere to send spans yet, hold them for AttachExporter
+		names = []string{"buffer"}
+	}
+
+	var procs []namedProcessor
+	for _, name := range names {
+		exporter, err := c.newSpanExporter(ctx, name, o)
 		if err != nil {
-			log.Printf("shutdown of OpenTelemetry OTLP exporter failed: %s", err)
+			if name == "otlp" {
+				shutdownProcessors(ctx, procs)
+				return nil, err
+			}
+			logger().Error("failed to configure trace exporter, skipping it", "exporter", name, "error", err)
+			continue
+		}
+		if exporter == nil {
+			continue
 		}
+
+		// TODO: more configuration opportunities here
+		bsp := sdktrace.NewBatchSpanProcessor(namedExporter{name: name, SpanExporter: exporter})
+		procs = append(procs, namedProcessor{name: name, SpanProcessor: bsp})
+	}
+
+	return procs, nil
+}
+
+// newTraceExporter creates the OTLP trace exporter for c.Endpoint.
+func (c Config) newTraceExporter(ctx context.Context, o options) (*otlptrace.Exporter, error) {
+	grpcOpts := []otlpgrpc.Option{
+		otlpgrpc.WithEndpoint(c.grpcTarget()),
+		otlpgrpc.WithTLSCredentials(c.transportCredentials()),
+		otlpgrpc.WithDialOption(c.dialOptions(o.dialOptions...)...),
+	}
+
+	if c.Timeout > 0 {
+		grpcOpts = append(grpcOpts, otlpgrpc.WithTimeout(c.Timeout))
+	}
+	if c.TracesCompression == "gzip" {
+		grpcOpts = append(grpcOpts, otlpgrpc.WithCompressor(c.TracesCompression))
 	}
+	grpcOpts = append(grpcOpts, otlpgrpc.WithRetry(otlpgrpc.RetryConfig(c.retryConfig())))
+
+	return otlpgrpc.New(ctx, grpcOpts...)
 }
@@ -0,0 +1,77 @@
+package otelinit
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// unixScheme is the prefix for endpoints that are Unix domain sockets, e.g.
+// unix:///run/otel/otlp.sock. gRPC understands this target syntax natively.
+const unixScheme = "unix://"
+
+// isUnixSocket reports whether the endpoint is a Unix domain socket.
+func (c Config) isUnixSocket() bool {
+	return strings.HasPrefix(c.Endpoint, unixScheme)
+}
+
+// transportCredentials returns the gRPC transport credentials for the
+// configured endpoint.
+func (c Config) transportCredentials() credentials.TransportCredentials {
+	if c.Insecure {
+		return insecure.NewCredentials()
+	}
+	if cfg := c.tlsConfig(); cfg != nil {
+		return credentials.NewTLS(cfg)
+	}
+	return credentials.NewClientTLSFromCert(nil, "")
+}
+
+// roundRobinServiceConfig makes gRPC spread RPCs over every address the
+// resolver returns instead of sticking to the first one.
+const roundRobinServiceConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+
+// dialOptions returns the gRPC dial options shared by all of the exporters,
+// followed by any extra ones passed in from code.
+func (c Config) dialOptions(extra ...grpc.DialOption) []grpc.DialOption {
+	var opts []grpc.DialOption
+	if c.isUnixSocket() {
+		opts = append(opts, grpc.WithContextDialer(dialUnix))
+	} else if c.isSRVEndpoint() {
+		opts = append(opts, grpc.WithResolvers(c.newSRVResolverBuilder()))
+	} else if proxy := c.proxyURL(); proxy != nil {
+		opts = append(opts, grpc.WithContextDialer(dialProxy(proxy)))
+	}
+	if creds := c.newFileCredentials(); creds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(creds))
+	}
+	if creds := c.newOAuth2Credentials(); creds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(creds))
+	}
+	if c.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.KeepaliveTime,
+			Timeout:             c.KeepaliveTimeout,
+			PermitWithoutStream: c.KeepalivePermitWithoutStream,
+		}))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(c.MaxSendMsgSize)))
+	}
+	if c.LoadBalancing == "round_robin" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(roundRobinServiceConfig))
+	}
+	return append(opts, extra...)
+}
+
+// dialUnix connects to a Unix domain socket. Depending on which resolver
+// gRPC picks, the address may or may not still have the scheme on it.
+func dialUnix(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", strings.TrimPrefix(addr, unixScheme))
+}
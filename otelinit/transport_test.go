@@ -0,0 +1,105 @@
+package otelinit
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+)
+
+func TestUnixSocketEndpoint(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "otlp.sock")
+	fc := startFakeCollector(t, "unix", sock)
+
+	ctx := context.Background()
+	c := Config{
+		Servicename: testServiceName,
+		Endpoint:    "unix://" + sock,
+		Insecure:    true,
+	}
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), options{})
+
+	_, span := otel.Tracer("test").Start(ctx, "over a unix socket")
+	span.End()
+
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"over a unix socket"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+}
+
+func TestGRPCTarget(t *testing.T) {
+	tests := map[string]struct {
+		c    Config
+		want string
+	}{
+		"plain endpoint": {
+			c:    Config{Endpoint: "collector:4317"},
+			want: "collector:4317",
+		},
+		"round robin uses the dns resolver": {
+			c:    Config{Endpoint: "collector:4317", LoadBalancing: "round_robin"},
+			want: "dns:///collector:4317",
+		},
+		"proxy passes the name through": {
+			c:    Config{Endpoint: "collector:4317", LoadBalancing: "round_robin", Proxy: "http://proxy:3128"},
+			want: "passthrough:///collector:4317",
+		},
+		"unix sockets are left alone": {
+			c:    Config{Endpoint: "unix:///run/otel/otlp.sock", LoadBalancing: "round_robin"},
+			want: "unix:///run/otel/otlp.sock",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.c.grpcTarget(); got != tc.want {
+				t.Errorf("expected target %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDialTuningExport(t *testing.T) {
+	fc := startFakeCollector(t, "tcp", "127.0.0.1:0")
+	_, port, _ := net.SplitHostPort(fc.addr)
+
+	ctx := context.Background()
+	c := Config{
+		Servicename:                  testServiceName,
+		Endpoint:                     net.JoinHostPort("localhost", port),
+		Insecure:                     true,
+		KeepaliveTime:                time.Minute,
+		KeepaliveTimeout:             10 * time.Second,
+		KeepalivePermitWithoutStream: true,
+		MaxSendMsgSize:               16 << 20,
+		LoadBalancing:                "round_robin",
+	}
+	o := newOptions([]Option{WithDialOptions(grpc.WithUserAgent("otelinit-test"))})
+	_, shutdown := c.initTracing(ctx, c.newResource(ctx), o)
+
+	_, span := otel.Tracer("test").Start(ctx, "tuned")
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %s", err)
+	}
+
+	if diff := cmp.Diff([]string{"tuned"}, fc.spanNames()); diff != "" {
+		t.Errorf("collector did not receive the expected spans (-want +got):\n%s", diff)
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if ua := fc.metadata[0].Get("user-agent"); len(ua) == 0 || !strings.HasPrefix(ua[0], "otelinit-test") {
+		t.Errorf("expected the extra dial option to set the user agent, got %q", ua)
+	}
+}